@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"go-news-api/models/request"
+	"go-news-api/utils"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditExportFormat string
+	auditExportOutput string
+	auditExportFilter request.AuditQueryRequest
+)
+
+var auditExportCmd = &cobra.Command{
+	Use:   "audit-export",
+	Short: "Export the audit log",
+	Long: `This command writes the audit log, oldest first, as JSON lines or CSV to a file or standard output.
+The same filters as GET /api/audit can be given as flags.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := utils.Validate.Struct(&auditExportFilter); err != nil {
+			fmt.Printf("Error exporting audit log: %v\n", err)
+			return
+		}
+
+		var out io.Writer = os.Stdout
+		if auditExportOutput != "" {
+			file, err := os.Create(auditExportOutput)
+			if err != nil {
+				fmt.Printf("Error exporting audit log: %v\n", err)
+				return
+			}
+			defer file.Close()
+			out = file
+		}
+
+		count, err := utils.ExportAuditEvents(out, &auditExportFilter, auditExportFormat)
+		if err != nil {
+			fmt.Printf("Error exporting audit log: %v\n", err)
+			return
+		}
+		if auditExportOutput != "" {
+			fmt.Printf("Exported %d audit event(s) to %s.\n", count, auditExportOutput)
+		}
+	},
+}
+
+func init() {
+	flags := auditExportCmd.Flags()
+	flags.StringVar(&auditExportFormat, "format", "json", "output format, json or csv")
+	flags.StringVarP(&auditExportOutput, "output", "o", "", "file to write to instead of standard output")
+	flags.UintVar(&auditExportFilter.ActorID, "actor-id", 0, "only events by this user ID")
+	flags.StringVar(&auditExportFilter.Action, "action", "", "only events with this action")
+	flags.StringVar(&auditExportFilter.Outcome, "outcome", "", "only events with this outcome")
+	flags.StringVar(&auditExportFilter.TargetType, "target-type", "", "only events on this target type")
+	flags.StringVar(&auditExportFilter.TargetID, "target-id", "", "only events on this target ID")
+	flags.StringVar(&auditExportFilter.From, "from", "", "only events on or after this date (YYYY-MM-DD)")
+	flags.StringVar(&auditExportFilter.To, "to", "", "only events on or before this date (YYYY-MM-DD)")
+	rootCmd.AddCommand(auditExportCmd)
+}
@@ -0,0 +1,46 @@
+//go:build mockoidc
+
+package cmd
+
+import (
+	"fmt"
+	"go-news-api/internal/mockoidc"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	mockOIDCPort     int
+	mockOIDCClientID string
+	mockOIDCEmail    string
+)
+
+var mockOIDCCmd = &cobra.Command{
+	Use:   "mock-oidc",
+	Short: "Run a local mock OpenID Connect provider",
+	Long: `This command runs a mock OpenID Connect provider for trying social login locally.
+It supports discovery, PKCE and nonces, and signs every user in without asking, as the email
+given in the login_hint parameter or --email. Configure it as a provider with
+OIDC_PROVIDERS=mock and OIDC_MOCK_ISSUER=http://localhost:<port>. It is only built with
+the mockoidc build tag, so it never ships in a production binary.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		provider, err := mockoidc.New(fmt.Sprintf("http://localhost:%d", mockOIDCPort), mockOIDCClientID, mockOIDCEmail)
+		if err != nil {
+			fmt.Printf("Error starting mock OIDC provider: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Mock OIDC provider listening on %s\n", provider.Issuer())
+		if err := http.ListenAndServe(fmt.Sprintf(":%d", mockOIDCPort), provider.Handler()); err != nil {
+			fmt.Printf("Error running mock OIDC provider: %v\n", err)
+		}
+	},
+}
+
+func init() {
+	mockOIDCCmd.Flags().IntVar(&mockOIDCPort, "port", 9000, "port to listen on")
+	mockOIDCCmd.Flags().StringVar(&mockOIDCClientID, "client-id", "go-news-api", "client ID to accept")
+	mockOIDCCmd.Flags().StringVar(&mockOIDCEmail, "email", "mock.user@example.com", "email of the signed in user when no login_hint is given")
+	rootCmd.AddCommand(mockOIDCCmd)
+}
@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"encoding/json"
+	"go-news-api/database"
+	"go-news-api/internal/mockoidc"
+	"go-news-api/models/entity"
+	"go-news-api/routes"
+	"go-news-api/utils"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const mockOIDCTestClientID = "go-news-api"
+
+// oidcTest drives the OIDC endpoints of the API against the mock provider.
+type oidcTest struct {
+	t        *testing.T
+	app      *fiber.App
+	provider *http.Client
+}
+
+func TestMockOIDCFlow(t *testing.T) {
+	openTestDatabase(t)
+
+	// The issuer is only known once the server listens
+	var provider *mockoidc.Provider
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provider.Handler().ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	var err error
+	if provider, err = mockoidc.New(server.URL, mockOIDCTestClientID, "mock.user@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("OIDC_PROVIDERS", "mock")
+	t.Setenv("OIDC_MOCK_ISSUER", server.URL)
+	t.Setenv("OIDC_MOCK_CLIENT_ID", mockOIDCTestClientID)
+	t.Setenv("OIDC_MOCK_CLIENT_SECRET", "secret")
+	t.Setenv("OIDC_MOCK_REDIRECT_URL", "http://localhost:3000/api/auth/mock/callback")
+	t.Setenv("RATE_LIMIT_AUTH", "1000/1m")
+	t.Setenv("JWT_KEY_ENCRYPTION_KEY", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+
+	if err := utils.EnsureSigningKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	routes.RouteInit(app)
+
+	test := &oidcTest{
+		t:   t,
+		app: app,
+		provider: &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}},
+	}
+
+	t.Run("signs up a new user", func(t *testing.T) {
+		callback, cookie := test.login("new.user@example.com")
+
+		status, body := test.get(callback, cookie, "")
+		if status != fiber.StatusOK {
+			t.Fatalf("callback returned %d: %s", status, body)
+		}
+
+		user := findUser(t, "new.user@example.com")
+		if !user.IsVerified || user.Password != "" {
+			t.Fatalf("expected a verified user without a password, got %+v", user)
+		}
+		if countIdentities(t, user.ID) != 1 {
+			t.Fatal("expected the identity to be linked")
+		}
+
+		// A callback cannot be replayed
+		if status, body := test.get(callback, cookie, ""); status != fiber.StatusBadRequest {
+			t.Fatalf("replayed callback returned %d: %s", status, body)
+		}
+	})
+
+	t.Run("signs in a known identity", func(t *testing.T) {
+		callback, cookie := test.login("new.user@example.com")
+
+		if status, body := test.get(callback, cookie, ""); status != fiber.StatusOK {
+			t.Fatalf("callback returned %d: %s", status, body)
+		}
+	})
+
+	t.Run("rejects a callback from another browser", func(t *testing.T) {
+		callback, _ := test.login("other.browser@example.com")
+
+		if status, body := test.get(callback, nil, ""); status != fiber.StatusBadRequest {
+			t.Fatalf("callback without the binding cookie returned %d: %s", status, body)
+		}
+
+		_, otherCookie := test.login("other.browser@example.com")
+		if status, body := test.get(callback, otherCookie, ""); status != fiber.StatusBadRequest {
+			t.Fatalf("callback with the cookie of another request returned %d: %s", status, body)
+		}
+	})
+
+	t.Run("links to a verified user with the same email", func(t *testing.T) {
+		user := createUser(t, "verified@example.com", true)
+		callback, cookie := test.login(user.Email)
+
+		if status, body := test.get(callback, cookie, ""); status != fiber.StatusOK {
+			t.Fatalf("callback returned %d: %s", status, body)
+		}
+		if countIdentities(t, user.ID) != 1 {
+			t.Fatal("expected the identity to be linked to the existing user")
+		}
+	})
+
+	t.Run("refuses to link an unverified user with the same email", func(t *testing.T) {
+		user := createUser(t, "unverified@example.com", false)
+		callback, cookie := test.login(user.Email)
+
+		if status, body := test.get(callback, cookie, ""); status != fiber.StatusConflict {
+			t.Fatalf("callback returned %d: %s", status, body)
+		}
+		if countIdentities(t, user.ID) != 0 {
+			t.Fatal("expected no identity to be linked")
+		}
+	})
+
+	t.Run("links a provider from the profile", func(t *testing.T) {
+		user := createUser(t, "linking@example.com", false)
+		callback, cookie := test.link(user, "linked.account@example.com")
+
+		if status, body := test.get(callback, cookie, ""); status != fiber.StatusOK {
+			t.Fatalf("callback returned %d: %s", status, body)
+		}
+		if countIdentities(t, user.ID) != 1 {
+			t.Fatal("expected the identity to be linked")
+		}
+	})
+
+	t.Run("a link cannot be completed by someone else", func(t *testing.T) {
+		attacker := createUser(t, "attacker@example.com", true)
+		callback, _ := test.link(attacker, "victim.account@example.com")
+
+		// The victim opens the attacker's authorization URL in their own browser
+		if status, body := test.get(callback, nil, ""); status != fiber.StatusBadRequest {
+			t.Fatalf("callback returned %d: %s", status, body)
+		}
+		if countIdentities(t, attacker.ID) != 0 {
+			t.Fatal("expected no identity to be linked to the attacker")
+		}
+	})
+}
+
+// login starts a sign-in and signs in at the mock provider as email. It returns the callback the provider
+// redirects to and the binding cookie of the browser that started it.
+func (test *oidcTest) login(email string) (string, *http.Cookie) {
+	resp, err := test.app.Test(httptest.NewRequest(http.MethodGet, "/api/auth/mock/login", nil), -1)
+	if err != nil {
+		test.t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusFound {
+		test.t.Fatalf("login returned %d", resp.StatusCode)
+	}
+
+	return test.authorize(resp.Header.Get(fiber.HeaderLocation), email), bindingCookie(test.t, resp)
+}
+
+// link starts linking a provider to the user and signs in at the mock provider as email.
+func (test *oidcTest) link(user *entity.User, email string) (string, *http.Cookie) {
+	_, tokens, err := utils.CreateSession(user, "test", "127.0.0.1")
+	if err != nil {
+		test.t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/profile/identities/mock", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tokens.AccessToken)
+	resp, err := test.app.Test(req, -1)
+	if err != nil {
+		test.t.Fatal(err)
+	}
+
+	var body struct {
+		Data struct {
+			AuthorizationURL string `json:"authorization_url"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Data.AuthorizationURL == "" {
+		test.t.Fatalf("link returned %d without an authorization URL", resp.StatusCode)
+	}
+
+	return test.authorize(body.Data.AuthorizationURL, email), bindingCookie(test.t, resp)
+}
+
+// authorize signs in at the mock provider and returns the path and query of the callback it redirects to.
+func (test *oidcTest) authorize(authURL, email string) string {
+	resp, err := test.provider.Get(authURL + "&login_hint=" + url.QueryEscape(email))
+	if err != nil {
+		test.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		body, _ := io.ReadAll(resp.Body)
+		test.t.Fatalf("provider returned %d: %s", resp.StatusCode, body)
+	}
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		test.t.Fatal(err)
+	}
+	return callback.RequestURI()
+}
+
+func (test *oidcTest) get(path string, cookie *http.Cookie, token string) (int, string) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	if token != "" {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+
+	resp, err := test.app.Test(req, -1)
+	if err != nil {
+		test.t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func bindingCookie(t *testing.T, resp *http.Response) *http.Cookie {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == utils.OIDCBindingCookie && cookie.Value != "" {
+			return cookie
+		}
+	}
+	t.Fatal("expected the binding cookie to be set")
+	return nil
+}
+
+func createUser(t *testing.T, email string, verified bool) *entity.User {
+	password, err := utils.HashPassword("password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := entity.User{Name: strings.Split(email, "@")[0], Email: email, Password: password, Role: entity.ReaderRole, IsVerified: verified}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
+
+func findUser(t *testing.T, email string) *entity.User {
+	var user entity.User
+	if err := database.DB.First(&user, "email = ?", email).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
+
+func countIdentities(t *testing.T, userID uint) int64 {
+	var count int64
+	if err := database.DB.Model(&entity.UserIdentity{}).Where("user_id = ? AND provider = ?", userID, "mock").Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+// openTestDatabase replaces the database with an in-memory SQLite database. MySQL enum columns are created
+// as text, since SQLite does not know them.
+func openTestDatabase(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	models := []interface{}{&entity.User{}, &entity.Session{}, &entity.RefreshToken{}, &entity.UserIdentity{}, &entity.OAuthState{}, &entity.SigningKey{}, &entity.AuditEvent{}}
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if strings.HasPrefix(string(field.DataType), "enum") {
+				field.DataType = "text"
+			}
+		}
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"fmt"
+	"go-news-api/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var publishDueCmd = &cobra.Command{
+	Use:   "publish-due",
+	Short: "Publish scheduled articles whose publish time has passed",
+	Long:  `This command will publish every approved article whose publish time has passed. It can be run from cron.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		count, err := utils.PublishDueArticles()
+		if err != nil {
+			fmt.Printf("Error publishing due articles: %v\n", err)
+			return
+		}
+		fmt.Printf("Published %d scheduled article(s).\n", count)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(publishDueCmd)
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"fmt"
+	"go-news-api/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var purgeAccountsCmd = &cobra.Command{
+	Use:   "purge-accounts",
+	Short: "Delete accounts whose deletion grace period has passed",
+	Long:  `This command will delete every account that was scheduled for deletion and whose grace period has passed. It can be run from cron.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		count, err := utils.PurgeDeletedAccounts()
+		if err != nil {
+			fmt.Printf("Error purging deleted accounts: %v\n", err)
+			return
+		}
+		fmt.Printf("Deleted %d account(s).\n", count)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(purgeAccountsCmd)
+}
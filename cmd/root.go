@@ -11,7 +11,13 @@ var rootCmd = &cobra.Command{
 	Use: "go-news-api",
 }
 
-func Execute() {
+// Execute runs the command line. serve is called when no subcommand is given,
+// so subcommands such as seed and publish-due exit without starting the server.
+func Execute(serve func()) {
+	rootCmd.Run = func(cmd *cobra.Command, args []string) {
+		serve()
+	}
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
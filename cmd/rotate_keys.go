@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"go-news-api/utils"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	rotateKeysAlgorithm  string
+	rotateKeysActivateIn time.Duration
+	rotateKeysGrace      time.Duration
+	rotateKeysList       bool
+)
+
+var rotateKeysCmd = &cobra.Command{
+	Use:   "rotate-keys",
+	Short: "Rotate the keys that sign JWTs",
+	Long: `This command generates a new JWT signing key and schedules the current keys to retire.
+The new key is published in /.well-known/jwks.json right away and starts signing tokens after
+--activate-in, which gives services caching the JWKS time to pick it up. The old keys keep
+verifying tokens for --grace after that. Use --activate-in 0 --grace 0 to replace them at once,
+e.g. when a key leaked.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !rotateKeysList {
+			if rotateKeysActivateIn > 0 && rotateKeysActivateIn < utils.KeyActivationDelay {
+				fmt.Printf("Warning: services may cache the JWKS for up to %s, tokens signed before they reload it will fail to verify.\n", utils.KeyActivationDelay)
+			}
+
+			key, err := utils.RotateSigningKeys(rotateKeysAlgorithm, rotateKeysActivateIn, rotateKeysGrace)
+			if err != nil {
+				fmt.Printf("Error rotating signing keys: %v\n", err)
+				return
+			}
+			fmt.Printf("Generated %s key %s, signing from %s.\n", key.Algorithm, key.Kid, key.ActivatesAt.Format(time.RFC3339))
+		}
+
+		keys, err := utils.GetSigningKeys()
+		if err != nil {
+			fmt.Printf("Error getting signing keys: %v\n", err)
+			return
+		}
+		for _, key := range keys {
+			retires := "never"
+			if key.RetiresAt != nil {
+				retires = key.RetiresAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%s  %-5s  activates %s  retires %s\n", key.Kid, key.Algorithm, key.ActivatesAt.Format(time.RFC3339), retires)
+		}
+	},
+}
+
+func init() {
+	rotateKeysCmd.Flags().StringVar(&rotateKeysAlgorithm, "algorithm", utils.SigningAlgorithmRS256, "algorithm of the new key, RS256 or EdDSA")
+	rotateKeysCmd.Flags().DurationVar(&rotateKeysActivateIn, "activate-in", utils.KeyActivationDelay, "how long the new key is only published before it signs tokens, at least the JWKS cache max-age")
+	rotateKeysCmd.Flags().DurationVar(&rotateKeysGrace, "grace", 15*time.Minute, "how long the old keys keep verifying tokens once the new key signs, at least the access token TTL")
+	rotateKeysCmd.Flags().BoolVar(&rotateKeysList, "list", false, "only list the signing keys")
+	rootCmd.AddCommand(rotateKeysCmd)
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"go-news-api/models/entity"
+	"go-news-api/utils"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+var setRoleCmd = &cobra.Command{
+	Use:   "set-role <email> <role>",
+	Short: "Change the role of a user",
+	Long: `This command sets the role of the user with the email to admin, editor, author or reader.
+Use it to promote the first admin of a deployment, who can then manage roles through the API.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		user, err := utils.SetUserRole(args[0], entity.UserRole(args[1]))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			fmt.Printf("Error setting role: no user with email %s\n", args[0])
+			return
+		}
+		if err != nil {
+			fmt.Printf("Error setting role: %v\n", err)
+			return
+		}
+		fmt.Printf("%s is now %s.\n", user.Email, user.Role)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(setRoleCmd)
+}
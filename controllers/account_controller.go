@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-news-api/models/entity"
+	"go-news-api/models/request"
+	"go-news-api/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ExportAccount godoc
+// @Summary Export personal data
+// @Description Downloads the personal data of the currently authenticated user: the profile, articles, comments, sessions, linked identities and API keys. The ZIP archive also contains the uploaded avatar and article thumbnails, while the JSON format only has the data.
+// @Tags Profile
+// @Produce  application/zip
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param format query string false "zip (default) or json"
+// @Router /profile/export [get]
+func ExportAccount(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to export account", errors.New("user not found"))
+	}
+
+	request := new(request.ExportAccountRequest)
+
+	// Parse query parameters
+	if err := ctx.QueryParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to export account", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to export account", err)
+	}
+
+	// Collect personal data
+	export, err := utils.ExportAccount(user.ID)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to export account", err)
+	}
+
+	fileName := fmt.Sprintf("account-%d-%s", user.ID, export.ExportedAt.Format("20060102"))
+	var body bytes.Buffer
+	if request.Format == "json" {
+		encoder := json.NewEncoder(&body)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(export); err != nil {
+			return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to export account", err)
+		}
+		ctx.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		fileName += ".json"
+	} else {
+		if err := export.WriteZip(&body); err != nil {
+			return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to export account", err)
+		}
+		ctx.Set(fiber.HeaderContentType, "application/zip")
+		fileName += ".zip"
+	}
+
+	utils.Audit(ctx, "account_export", "success", user.ID, user.Email)
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	return ctx.Status(fiber.StatusOK).Send(body.Bytes())
+}
+
+// DeleteAccount godoc
+// @Summary Delete account
+// @Description Schedules the currently authenticated account for deletion after a grace period, during which the user can still sign in and restore it. Content set to anonymize keeps the user's articles and comments under a "Deleted user" placeholder, while delete removes them with the account. Accounts with a password must confirm it. Accounts created with social login have none, so the first request emails them a code with a 202 response, and the request has to be repeated with it as otp.
+// @Tags Profile
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param password formData string false "Current Password"
+// @Param otp formData string false "Emailed code, for accounts without a password"
+// @Param content formData string true "anonymize or delete"
+// @Router /profile [delete]
+func DeleteAccount(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to delete account", errors.New("user not found"))
+	}
+
+	request := new(request.DeleteAccountRequest)
+
+	// Parse request body
+	if err := ctx.BodyParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to delete account", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to delete account", err)
+	}
+
+	// Check current password, or an emailed code for accounts created with social login that have none
+	switch {
+	case user.Password != "":
+		if err := utils.VerifyPassword(request.Password, user.Password); err != nil {
+			utils.Audit(ctx, "account_deletion", "wrong_password", user.ID, user.Email)
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to delete account", utils.ErrWrongPassword)
+		}
+	case request.Otp == "":
+		otp, err := utils.IssueOtp(user.ID, entity.AccountDeletion)
+		if err != nil {
+			utils.Audit(ctx, "account_deletion", otpOutcome(err), user.ID, user.Email)
+			return utils.SendErrorResponse(ctx, utils.OtpErrorStatus(err), "Failed to delete account", err)
+		}
+
+		record := utils.NewAuditRecord(ctx, "account_deletion", "code_sent", user.ID, user.Email)
+		utils.WriteAudit(record)
+		sendAuditedEmail(record, user.Email, "Confirm your account deletion", "views/emails/account_deletion_code.html", fiber.Map{
+			"name": user.Name,
+			"otp":  otp,
+		})
+		return utils.SendSuccessResponse(ctx, fiber.StatusAccepted, "A confirmation code has been sent to your email, repeat the request with it as otp")
+	default:
+		if err := utils.ConsumeOtp(user.ID, entity.AccountDeletion, request.Otp, func(tx *gorm.DB) error { return nil }); err != nil {
+			utils.Audit(ctx, "account_deletion", otpOutcome(err), user.ID, user.Email)
+			return utils.SendErrorResponse(ctx, utils.OtpErrorStatus(err), "Failed to delete account", err)
+		}
+	}
+
+	// Schedule deletion
+	if err := utils.ScheduleAccountDeletion(user, entity.DeletionMode(request.Content)); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete account", err)
+	}
+
+	// Notify user
+	record := utils.NewAuditRecord(ctx, "account_deletion", "scheduled", user.ID, user.Email)
+	utils.WriteAudit(record)
+	sendAuditedEmail(record, user.Email, "Your account will be deleted", "views/emails/account_deletion.html", fiber.Map{
+		"name":      user.Name,
+		"delete_at": user.DeletionScheduledAt.Format(time.RFC1123),
+		"anonymize": user.DeletionMode == entity.AnonymizeContent,
+	})
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Account has been scheduled for deletion", fiber.Map{
+		"deletion_scheduled_at": user.DeletionScheduledAt,
+		"deletion_mode":         user.DeletionMode,
+	})
+}
+
+// RestoreAccount godoc
+// @Summary Restore account
+// @Description Cancels the scheduled deletion of the currently authenticated account.
+// @Tags Profile
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Router /profile/restore [post]
+func RestoreAccount(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to restore account", errors.New("user not found"))
+	}
+
+	// Cancel deletion
+	if err := utils.CancelAccountDeletion(user); err != nil {
+		if errors.Is(err, utils.ErrDeletionNotScheduled) {
+			return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to restore account", err)
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to restore account", err)
+	}
+
+	utils.Audit(ctx, "account_deletion", "cancelled", user.ID, user.Email)
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully restored account")
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"go-news-api/models/request"
+	"go-news-api/utils"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAPIKeys godoc
+// @Summary Get API keys
+// @Description Lists the personal API keys of the authenticated user, with their scopes, expiry and when they were last used. The keys themselves are never shown again.
+// @Tags API Keys
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Router /api-keys [get]
+func GetAPIKeys(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to get API keys", errors.New("user not found"))
+	}
+
+	// Fetch keys
+	var apiKeys []entity.APIKey
+	if err := database.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&apiKeys).Error; err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get API keys", err)
+	}
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully get API keys", fiber.Map{
+		"api_keys":         apiKeys,
+		"available_scopes": utils.APIKeyScopes,
+	})
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Creates a named personal API key limited to the given scopes, like articles:write or comments:read. Send it in the X-API-Key header or as the Bearer token. The key is only shown in this response.
+// @Tags API Keys
+// @Accept  json
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param request body request.CreateAPIKeyRequest true "API key"
+// @Router /api-keys [post]
+func CreateAPIKey(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to create API key", errors.New("user not found"))
+	}
+
+	request := new(request.CreateAPIKeyRequest)
+
+	// Parse request body
+	if err := ctx.BodyParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to create API key", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to create API key", err)
+	}
+
+	// Parse expiry
+	var expiresAt *time.Time
+	if request.ExpiresAt != "" {
+		parsed, err := time.Parse(time.RFC3339, request.ExpiresAt)
+		if err != nil {
+			return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to create API key", err)
+		}
+		if !parsed.After(time.Now()) {
+			return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to create API key", errors.New("expires_at must be in the future"))
+		}
+		expiresAt = &parsed
+	}
+
+	// Create key
+	apiKey, key, err := utils.CreateAPIKey(user, request.Name, request.Scopes, expiresAt)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to create API key", err)
+	}
+
+	utils.AuditChange(ctx, "api_key_create", "api_key", apiKey.ID, nil, apiKey)
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusCreated, "Successfully created API key, copy it now because it will not be shown again", fiber.Map{
+		"api_key": apiKey,
+		"key":     key,
+	})
+}
+
+// DeleteAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revokes one of the authenticated user's API keys. Requests made with it are rejected right away.
+// @Tags API Keys
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "API Key ID"
+// @Router /api-keys/{id} [delete]
+func DeleteAPIKey(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to revoke API key", errors.New("user not found"))
+	}
+
+	// Parse id
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to revoke API key", errors.New("invalid API key id"))
+	}
+
+	// Revoke key
+	revoked, err := utils.RevokeAPIKey(user.ID, uint(id))
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to revoke API key", err)
+	}
+	if !revoked {
+		return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to revoke API key", errors.New("API key not found"))
+	}
+
+	utils.AuditChange(ctx, "api_key_revoke", "api_key", id, nil, nil)
+
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully revoked API key")
+}
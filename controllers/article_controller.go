@@ -13,63 +13,109 @@ import (
 
 // GetAllArticles godoc
 // @Summary Get all articles
-// @Description Retrieves a list of all articles along with their related category, author, comments, and tags.
+// @Description Retrieves a page of published articles whose publish time has passed, along with their related category, author, tags and comment count. Supports page or cursor pagination, filtering and sorting.
 // @Tags Articles
 // @Accept  json
 // @Produce  json
+// @Param page query int false "Page number"
+// @Param per_page query int false "Articles per page (max 100)"
+// @Param cursor query string false "Cursor from a previous page, takes precedence over page"
+// @Param sort query string false "Sort by created_at, updated_at, title or popularity"
+// @Param order query string false "Sort order, asc or desc"
+// @Param category_id query int false "Category ID"
+// @Param tag query string false "Tag name"
+// @Param author_id query int false "Author ID"
+// @Param from query string false "Created on or after (YYYY-MM-DD)"
+// @Param to query string false "Created on or before (YYYY-MM-DD)"
 // @Router /articles [get]
 func GetAllArticles(ctx *fiber.Ctx) error {
-	var articles []entity.Article
+	request := new(request.ArticleQueryRequest)
 
-	// Fetch all articles
-	if err := database.DB.Preload("Category").
-		Preload("Author").
-		Preload("Comments.User").
-		Preload("Tags").
-		Find(&articles).Error; err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch articles", err)
+	// Parse query parameters
+	if err := ctx.QueryParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch articles", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch articles", err)
+	}
+
+	// Fetch published articles
+	query := database.DB.Model(&entity.Article{}).Scopes(utils.PublicArticles, utils.FilterArticles(request))
+	articles, pagination, err := utils.ArticlePaginator.Paginate(ctx, query, request.PageRequest)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, utils.ErrInvalidQuery) {
+			status = fiber.StatusBadRequest
+		}
+		return utils.SendErrorResponse(ctx, status, "Failed to fetch articles", err)
 	}
 
-	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully fetched articles", fiber.Map{
+	return utils.SendPaginatedResponse(ctx, fiber.StatusOK, "Successfully fetched articles", fiber.Map{
 		"articles":       articles,
-		"total_articles": len(articles),
-	})
+		"total_articles": pagination.Total,
+	}, pagination)
 }
 
 // GetMyArticles godoc
 // @Summary Get all articles by the authenticated user
-// @Description Retrieves a list of all articles created by the currently authenticated user along with their related category, author, comments, and tags.
+// @Description Retrieves a page of articles created by the currently authenticated user, whatever their status, along with their related category, author, tags and comment count. Supports the same pagination, filters and sorting as the article list.
 // @Tags Articles
 // @Accept  json
 // @Produce  json
 // @Param Authorization header string true "Bearer token"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Articles per page (max 100)"
+// @Param cursor query string false "Cursor from a previous page, takes precedence over page"
+// @Param sort query string false "Sort by created_at, updated_at, title or popularity"
+// @Param order query string false "Sort order, asc or desc"
+// @Param category_id query int false "Category ID"
+// @Param tag query string false "Tag name"
+// @Param status query string false "Article status"
+// @Param from query string false "Created on or after (YYYY-MM-DD)"
+// @Param to query string false "Created on or before (YYYY-MM-DD)"
 // @Router /articles/me [get]
 func GetMyArticles(ctx *fiber.Ctx) error {
 	// Get User
 	user := ctx.Locals("user").(*entity.User)
 	if user == nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to create article", errors.New("user not found"))
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to fetch articles", errors.New("user not found"))
 	}
 
-	// Fetch all articles
-	var articles []entity.Article
-	if err := database.DB.Preload("Category").
-		Preload("Author").
-		Preload("Comments.User").
-		Preload("Tags").
-		Where("author_id = ?", user.ID).Find(&articles).Error; err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch articles", err)
+	request := new(request.ArticleQueryRequest)
+
+	// Parse query parameters
+	if err := ctx.QueryParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch articles", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch articles", err)
 	}
 
-	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully fetched articles", fiber.Map{
+	// Fetch the user's articles
+	request.AuthorID = user.ID
+	query := database.DB.Model(&entity.Article{}).Scopes(utils.FilterArticles(request))
+	articles, pagination, err := utils.ArticlePaginator.Paginate(ctx, query, request.PageRequest)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, utils.ErrInvalidQuery) {
+			status = fiber.StatusBadRequest
+		}
+		return utils.SendErrorResponse(ctx, status, "Failed to fetch articles", err)
+	}
+
+	return utils.SendPaginatedResponse(ctx, fiber.StatusOK, "Successfully fetched articles", fiber.Map{
 		"articles":       articles,
-		"total_articles": len(articles),
-	})
+		"total_articles": pagination.Total,
+	}, pagination)
 }
 
 // GetArticleBySlug godoc
 // @Summary Get an article by its slug
-// @Description Retrieves a single article based on the provided slug, including its related category, author, comments, and tags.
+// @Description Retrieves a single published article based on the provided slug, including its related category, author, tags and comment count. Comments are paged through with GET /articles/{slug}/comments. A slug the article used before it was renamed gets a 301 response pointing at the current slug.
 // @Tags Articles
 // @Accept  json
 // @Produce  json
@@ -79,16 +125,16 @@ func GetArticleBySlug(ctx *fiber.Ctx) error {
 	// Get article slug from URL parameter
 	articleSlug := ctx.Params("slug")
 
-	// Find article by slug
+	// Find published article by slug
 	var article entity.Article
 	if err := database.DB.Preload("Category").
 		Preload("Author").
-		Preload("Comments.User").
 		Preload("Tags").
+		Scopes(utils.PublicArticles, utils.WithCommentCount).
 		First(&article, "slug = ?", articleSlug).Error; err != nil {
 		// If article not found
 		if err == gorm.ErrRecordNotFound {
-			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to fetch article", err)
+			return redirectOldArticleSlug(ctx, articleSlug, err)
 		}
 		// If error occurred
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch article", err)
@@ -99,15 +145,43 @@ func GetArticleBySlug(ctx *fiber.Ctx) error {
 	})
 }
 
+// redirectOldArticleSlug answers a request for a slug an article used before it was renamed
+// with a redirect to the current slug, or with the original not found error.
+func redirectOldArticleSlug(ctx *fiber.Ctx, oldSlug string, notFound error) error {
+	var history entity.ArticleSlug
+	if err := database.DB.First(&history, "slug = ?", oldSlug).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to fetch article", notFound)
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch article", err)
+	}
+
+	var article entity.Article
+	if err := database.DB.Scopes(utils.PublicArticles).First(&article, history.ArticleID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to fetch article", notFound)
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch article", err)
+	}
+
+	location := "/api/articles/" + article.Slug
+	ctx.Location(location)
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusMovedPermanently, "Article has moved", fiber.Map{
+		"slug":     article.Slug,
+		"location": location,
+	})
+}
+
 // CreateArticle godoc
 // @Summary Create a new article
-// @Description Creates a new article with the provided title, slug, content, category, author, thumbnail, and tags. The thumbnail is uploaded as a file and saved to the server.
+// @Description Creates a new draft article with the provided title, content, category, author, thumbnail, and tags. The slug is generated from the title, with a numeric suffix if it is taken, unless one is provided. The thumbnail is uploaded as a file and saved to the server. Requires the articles:write permission and a verified email, otherwise the error code is email_not_verified.
 // @Tags Articles
 // @Accept  multipart/form-data
 // @Produce  json
 // @Param Authorization header string true "Bearer token"
 // @Param title formData string true "Article Title"
-// @Param slug formData string true "Article Slug"
+// @Param slug formData string false "Article Slug"
 // @Param thumbnail formData file true "Article Thumbnail"
 // @Param content formData string true "Article Content"
 // @Param category_id formData int true "Category ID"
@@ -132,6 +206,25 @@ func CreateArticle(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to create article", err)
 	}
 
+	// Generate slug from the title unless one is given
+	var articleSlug string
+	if request.Slug != "" {
+		articleSlug = utils.GenerateSlug(request.Slug)
+		taken, err := utils.SlugTaken(articleSlug, 0)
+		if err != nil {
+			return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create article", err)
+		}
+		if taken {
+			return utils.SendErrorResponse(ctx, fiber.StatusConflict, "Failed to create article", errors.New("slug is already taken"))
+		}
+	} else {
+		generated, err := utils.UniqueArticleSlug(utils.GenerateSlug(request.Title), 0)
+		if err != nil {
+			return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create article", err)
+		}
+		articleSlug = generated
+	}
+
 	// Save the thumbnail file
 	thumbnailPath, err := utils.SaveImageFile(ctx, "thumbnail", "./public/uploads/thumbnails")
 	if err != nil {
@@ -141,9 +234,10 @@ func CreateArticle(ctx *fiber.Ctx) error {
 	// Create article
 	article := entity.Article{
 		Title:      request.Title,
-		Slug:       request.Slug,
+		Slug:       articleSlug,
 		Thumbnail:  thumbnailPath,
 		Content:    request.Content,
+		Status:     entity.Draft,
 		CategoryID: request.CategoryID,
 		AuthorID:   user.ID,
 	}
@@ -154,21 +248,23 @@ func CreateArticle(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create article", err)
 	}
 
-	if err := database.DB.Create(&article).Error; err != nil {
+	// Save article with its tags and first revision
+	if _, err := utils.SaveArticle(&article, article.Slug, tags, user.ID); err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create article", err)
 	}
+	utils.AuditChange(ctx, "article_create", "article", article.ID, nil, article)
 
-	// Associate tags
-	if err := utils.AssociateTagsWithArticle(article.ID, tags); err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create article", err)
-	}
+	// Keep search index up to date
+	utils.SyncSearchIndex(article.ID)
 
-	return utils.SendSuccessResponse(ctx, fiber.StatusCreated, "Successfully created article")
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusCreated, "Successfully created article", fiber.Map{
+		"article": article,
+	})
 }
 
 // UpdateArticle godoc
 // @Summary Update an existing article by its slug
-// @Description Updates the details of an existing article, including its title, slug, content, category, author, thumbnail, and tags. If a new thumbnail is provided, the old one will be replaced.
+// @Description Updates the details of an existing article, including its title, slug, content, category, author, thumbnail, and tags. If a new thumbnail is provided, the old one will be replaced. Only the author or an editor can update an article. Every update is recorded as a revision, and a changed slug keeps redirecting from the old one.
 // @Tags Articles
 // @Accept  multipart/form-data
 // @Produce  json
@@ -179,7 +275,7 @@ func CreateArticle(ctx *fiber.Ctx) error {
 // @Param thumbnail formData file false "Article Thumbnail"
 // @Param content formData string false "Article Content"
 // @Param category_id formData int false "Category ID"
-// @Param tags formData []string false "Article Tags (can be multiple)" collectionFormat(multi)
+// @Param tags formData []string false "Article Tags (can be multiple), the current tags are kept when omitted" collectionFormat(multi)
 // @Router /articles/{slug} [put]
 func UpdateArticle(ctx *fiber.Ctx) error {
 	articleSlug := ctx.Params("slug")
@@ -201,6 +297,12 @@ func UpdateArticle(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to update article", errors.New("user not found"))
 	}
 
+	// Check if the user is the author of the article or an editor
+	if !utils.CanManageArticle(user, &article) {
+		return utils.SendErrorResponse(ctx, fiber.StatusForbidden, "Failed to update article", errors.New("you are not allowed to update this article"))
+	}
+	before := utils.AuditSnapshot(article)
+
 	// Parse request body
 	request := new(request.UpdateArticleRequest)
 	if err := ctx.BodyParser(request); err != nil {
@@ -216,8 +318,18 @@ func UpdateArticle(ctx *fiber.Ctx) error {
 	if request.Title != nil {
 		article.Title = *request.Title
 	}
-	if request.Slug != nil {
-		article.Slug = *request.Slug
+	oldSlug := article.Slug
+	if request.Slug != nil && *request.Slug != "" {
+		article.Slug = utils.GenerateSlug(*request.Slug)
+		if article.Slug != oldSlug {
+			taken, err := utils.SlugTaken(article.Slug, article.ID)
+			if err != nil {
+				return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update article", err)
+			}
+			if taken {
+				return utils.SendErrorResponse(ctx, fiber.StatusConflict, "Failed to update article", errors.New("slug is already taken"))
+			}
+		}
 	}
 	if request.Content != nil {
 		article.Content = *request.Content
@@ -241,27 +353,33 @@ func UpdateArticle(ctx *fiber.Ctx) error {
 		article.Thumbnail = thumbnailPath
 	}
 
-	// Handle tags
-	tags, err := utils.CreateOrFindTags(request.Tags)
+	// Handle tags, keeping the current ones when the request has none
+	var tags []entity.Tag
+	var err error
+	if request.Tags != nil {
+		tags, err = utils.CreateOrFindTags(request.Tags)
+	} else {
+		err = database.DB.Model(&article).Association("Tags").Find(&tags)
+	}
 	if err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update article", err)
 	}
 
-	if err := database.DB.Save(&article).Error; err != nil {
+	// Save article with its slug history, tags and revision
+	if _, err := utils.SaveArticle(&article, oldSlug, tags, user.ID); err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update article", err)
 	}
+	utils.AuditChange(ctx, "article_update", "article", article.ID, before, article)
 
-	// Associate tags
-	if err := utils.AssociateTagsWithArticle(article.ID, tags); err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update article", err)
-	}
+	// Keep search index up to date
+	utils.SyncSearchIndex(article.ID)
 
 	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully updated article")
 }
 
 // DeleteArticle godoc
 // @Summary Delete an article by its slug
-// @Description Deletes an article specified by the slug from the database. Also deletes the associated thumbnail image from the server.
+// @Description Deletes an article specified by the slug from the database. Also deletes the associated thumbnail image from the server. Only the author or an editor can delete an article.
 // @Tags Articles
 // @Accept  json
 // @Produce  json
@@ -288,9 +406,9 @@ func DeleteArticle(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete article", err)
 	}
 
-	// Check if the user is the author of the article
-	if article.AuthorID != user.ID {
-		return utils.SendErrorResponse(ctx, fiber.StatusForbidden, "Failed to delete article", errors.New("you are not the author of this article"))
+	// Check if the user is the author of the article or an editor
+	if !utils.CanManageArticle(user, &article) {
+		return utils.SendErrorResponse(ctx, fiber.StatusForbidden, "Failed to delete article", errors.New("you are not allowed to delete this article"))
 	}
 
 	// Delete thumbnail
@@ -302,6 +420,10 @@ func DeleteArticle(ctx *fiber.Ctx) error {
 	if err := database.DB.Delete(&article).Error; err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete article", err)
 	}
+	utils.AuditChange(ctx, "article_delete", "article", article.ID, article, nil)
+
+	// Keep search index up to date
+	utils.SyncSearchIndex(article.ID)
 
 	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully deleted article")
 }
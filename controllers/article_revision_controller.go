@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"go-news-api/utils"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetArticleRevisions godoc
+// @Summary Get the revision history of an article
+// @Description Retrieves every revision of an article, newest first. Only the author of the article or an editor can view its history.
+// @Tags Article Revisions
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param slug path string true "Article Slug"
+// @Router /articles/{slug}/revisions [get]
+func GetArticleRevisions(ctx *fiber.Ctx) error {
+	article, status, err := findRevisableArticle(ctx)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, status, "Failed to fetch revisions", err)
+	}
+
+	// Fetch all revisions
+	var revisions []entity.ArticleRevision
+	if err := database.DB.Preload("Editor").
+		Where("article_id = ?", article.ID).
+		Order("version DESC").
+		Find(&revisions).Error; err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch revisions", err)
+	}
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully fetched revisions", fiber.Map{
+		"revisions":       revisions,
+		"total_revisions": len(revisions),
+	})
+}
+
+// GetArticleRevision godoc
+// @Summary Get a single revision of an article
+// @Description Retrieves the snapshot stored for one revision of an article. Only the author of the article or an editor can view its history.
+// @Tags Article Revisions
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param slug path string true "Article Slug"
+// @Param version path int true "Revision Version"
+// @Router /articles/{slug}/revisions/{version} [get]
+func GetArticleRevision(ctx *fiber.Ctx) error {
+	article, status, err := findRevisableArticle(ctx)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, status, "Failed to fetch revision", err)
+	}
+
+	revision, status, err := findArticleRevision(article.ID, ctx.Params("version"))
+	if err != nil {
+		return utils.SendErrorResponse(ctx, status, "Failed to fetch revision", err)
+	}
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully fetched revision", fiber.Map{
+		"revision": revision,
+	})
+}
+
+// DiffArticleRevisions godoc
+// @Summary Compare two revisions of an article
+// @Description Shows a line-level diff of the title, slug, content and tags between two revisions, along with the category change. Revisions longer than 5000 lines are refused with 422.
+// @Tags Article Revisions
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param slug path string true "Article Slug"
+// @Param from query int true "Older Revision Version"
+// @Param to query int true "Newer Revision Version"
+// @Router /articles/{slug}/revisions/diff [get]
+func DiffArticleRevisions(ctx *fiber.Ctx) error {
+	article, status, err := findRevisableArticle(ctx)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, status, "Failed to compare revisions", err)
+	}
+
+	from, status, err := findArticleRevision(article.ID, ctx.Query("from"))
+	if err != nil {
+		return utils.SendErrorResponse(ctx, status, "Failed to compare revisions", err)
+	}
+
+	to, status, err := findArticleRevision(article.ID, ctx.Query("to"))
+	if err != nil {
+		return utils.SendErrorResponse(ctx, status, "Failed to compare revisions", err)
+	}
+
+	// Compare each field
+	diff := fiber.Map{
+		"category_id": fiber.Map{
+			"from": from.CategoryID,
+			"to":   to.CategoryID,
+		},
+	}
+	fields := map[string][2]string{
+		"title":   {from.Title, to.Title},
+		"slug":    {from.Slug, to.Slug},
+		"content": {from.Content, to.Content},
+		"tags":    {strings.Join(from.Tags, "\n"), strings.Join(to.Tags, "\n")},
+	}
+	for field, texts := range fields {
+		lines, err := utils.DiffLines(texts[0], texts[1])
+		if err != nil {
+			return utils.SendErrorResponse(ctx, fiber.StatusUnprocessableEntity, "Failed to compare revisions", err)
+		}
+		diff[field] = lines
+	}
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully compared revisions", fiber.Map{
+		"from": from.Version,
+		"to":   to.Version,
+		"diff": diff,
+	})
+}
+
+// RestoreArticleRevision godoc
+// @Summary Restore an old revision of an article
+// @Description Copies the title, slug, content, category and tags of an old revision back onto the article and records the result as a new revision. The current thumbnail is kept.
+// @Tags Article Revisions
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param slug path string true "Article Slug"
+// @Param version path int true "Revision Version"
+// @Router /articles/{slug}/revisions/{version}/restore [post]
+func RestoreArticleRevision(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*entity.User)
+
+	article, status, err := findRevisableArticle(ctx)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, status, "Failed to restore revision", err)
+	}
+
+	revision, status, err := findArticleRevision(article.ID, ctx.Params("version"))
+	if err != nil {
+		return utils.SendErrorResponse(ctx, status, "Failed to restore revision", err)
+	}
+
+	// Check if the old slug has been taken by another article
+	taken, err := utils.SlugTaken(revision.Slug, article.ID)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to restore revision", err)
+	}
+	if taken {
+		return utils.SendErrorResponse(ctx, fiber.StatusConflict, "Failed to restore revision", errors.New("slug is already used by another article"))
+	}
+
+	// Check if the old category still exists
+	if err := database.DB.First(&entity.Category{}, "id = ?", revision.CategoryID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusConflict, "Failed to restore revision", errors.New("category of this revision no longer exists"))
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to restore revision", err)
+	}
+
+	// Restore article
+	before := utils.AuditSnapshot(article)
+	oldSlug := article.Slug
+	article.Title = revision.Title
+	article.Slug = revision.Slug
+	article.Content = revision.Content
+	article.CategoryID = revision.CategoryID
+
+	tags, err := utils.CreateOrFindTags(revision.Tags)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to restore revision", err)
+	}
+
+	// Save article and record the restore as a new revision
+	restored, err := utils.SaveArticle(article, oldSlug, tags, user.ID)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to restore revision", err)
+	}
+	utils.AuditChange(ctx, "article_restore", "article", article.ID, before, article)
+
+	// Keep search index up to date
+	utils.SyncSearchIndex(article.ID)
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully restored revision", fiber.Map{
+		"revision": restored,
+	})
+}
+
+// findRevisableArticle finds the article identified by the slug parameter and checks that the user may see its history.
+func findRevisableArticle(ctx *fiber.Ctx) (*entity.Article, int, error) {
+	// Get User
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return nil, fiber.StatusUnauthorized, errors.New("user not found")
+	}
+
+	// Check if article exists
+	var article entity.Article
+	if err := database.DB.First(&article, "slug = ?", ctx.Params("slug")).Error; err != nil {
+		// If article not found
+		if err == gorm.ErrRecordNotFound {
+			return nil, fiber.StatusNotFound, err
+		}
+		// If error occurred
+		return nil, fiber.StatusInternalServerError, err
+	}
+
+	// Check if the user is the author of the article or an editor
+	if !utils.CanManageArticle(user, &article) {
+		return nil, fiber.StatusForbidden, errors.New("you are not allowed to view the history of this article")
+	}
+
+	return &article, 0, nil
+}
+
+// findArticleRevision finds a revision of the article by its version number.
+func findArticleRevision(articleID uint, version string) (*entity.ArticleRevision, int, error) {
+	var revision entity.ArticleRevision
+	if err := database.DB.Preload("Editor").First(&revision, "article_id = ? AND version = ?", articleID, version).Error; err != nil {
+		// If revision not found
+		if err == gorm.ErrRecordNotFound {
+			return nil, fiber.StatusNotFound, err
+		}
+		// If error occurred
+		return nil, fiber.StatusInternalServerError, err
+	}
+
+	return &revision, 0, nil
+}
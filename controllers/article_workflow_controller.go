@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"go-news-api/models/request"
+	"go-news-api/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// SubmitArticle godoc
+// @Summary Submit an article for review
+// @Description Moves a draft article to review. Only the author of the article or an editor can submit it.
+// @Tags Articles
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param slug path string true "Article Slug"
+// @Router /articles/{slug}/submit [post]
+func SubmitArticle(ctx *fiber.Ctx) error {
+	return changeArticleStatus(ctx, "article_submit", entity.Draft, entity.Review, false, "Failed to submit article", "Successfully submitted article for review")
+}
+
+// ApproveArticle godoc
+// @Summary Approve an article
+// @Description Approves an article that is in review so that its author can publish it. Requires the review permission, and reviewers cannot approve their own articles.
+// @Tags Articles
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param slug path string true "Article Slug"
+// @Router /articles/{slug}/approve [post]
+func ApproveArticle(ctx *fiber.Ctx) error {
+	return changeArticleStatus(ctx, "article_approve", entity.Review, entity.Approved, true, "Failed to approve article", "Successfully approved article")
+}
+
+// RejectArticle godoc
+// @Summary Reject an article
+// @Description Sends an article that is in review back to draft with a reason. Requires the review permission, and reviewers cannot reject their own articles.
+// @Tags Articles
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param slug path string true "Article Slug"
+// @Param reason formData string true "Rejection Reason"
+// @Router /articles/{slug}/reject [post]
+func RejectArticle(ctx *fiber.Ctx) error {
+	request := new(request.RejectArticleRequest)
+
+	// Parse request body
+	if err := ctx.BodyParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to reject article", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to reject article", err)
+	}
+
+	return changeArticleStatus(ctx, "article_reject", entity.Review, entity.Draft, true, "Failed to reject article", "Successfully rejected article", func(article *entity.Article) {
+		article.RejectionReason = request.Reason
+	})
+}
+
+// PublishArticle godoc
+// @Summary Publish an article
+// @Description Publishes an approved article. Only the author of the article or an editor can publish it.
+// @Tags Articles
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param slug path string true "Article Slug"
+// @Router /articles/{slug}/publish [post]
+func PublishArticle(ctx *fiber.Ctx) error {
+	return changeArticleStatus(ctx, "article_publish", entity.Approved, entity.Published, false, "Failed to publish article", "Successfully published article")
+}
+
+// UnpublishArticle godoc
+// @Summary Unpublish an article
+// @Description Moves a published article back to draft. Only the author of the article or an editor can unpublish it.
+// @Tags Articles
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param slug path string true "Article Slug"
+// @Router /articles/{slug}/unpublish [post]
+func UnpublishArticle(ctx *fiber.Ctx) error {
+	return changeArticleStatus(ctx, "article_unpublish", entity.Published, entity.Draft, false, "Failed to unpublish article", "Successfully unpublished article")
+}
+
+// ScheduleArticle godoc
+// @Summary Schedule an article for publishing
+// @Description Sets the time at which an approved article is published automatically. Only the author of the article or an editor can schedule it.
+// @Tags Articles
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param slug path string true "Article Slug"
+// @Param publish_at formData string true "Publish time in RFC 3339 format"
+// @Router /articles/{slug}/schedule [post]
+func ScheduleArticle(ctx *fiber.Ctx) error {
+	request := new(request.ScheduleArticleRequest)
+
+	// Parse request body
+	if err := ctx.BodyParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to schedule article", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to schedule article", err)
+	}
+
+	publishAt, err := time.Parse(time.RFC3339, request.PublishAt)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to schedule article", err)
+	}
+	if !publishAt.After(time.Now()) {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to schedule article", errors.New("publish time must be in the future"))
+	}
+
+	return setArticleSchedule(ctx, "article_schedule", &publishAt, "Failed to schedule article", "Successfully scheduled article")
+}
+
+// UnscheduleArticle godoc
+// @Summary Cancel a scheduled publication
+// @Description Clears the publish time of an approved article. Only the author of the article or an editor can unschedule it.
+// @Tags Articles
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param slug path string true "Article Slug"
+// @Router /articles/{slug}/schedule [delete]
+func UnscheduleArticle(ctx *fiber.Ctx) error {
+	return setArticleSchedule(ctx, "article_unschedule", nil, "Failed to unschedule article", "Successfully unscheduled article")
+}
+
+// setArticleSchedule sets the publish time of the approved article identified by the slug parameter,
+// recording it in the audit log as action.
+func setArticleSchedule(ctx *fiber.Ctx, action string, publishAt *time.Time, failMessage, successMessage string) error {
+	// Get User
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, failMessage, errors.New("user not found"))
+	}
+
+	// Check if article exists
+	var article entity.Article
+	if err := database.DB.First(&article, "slug = ?", ctx.Params("slug")).Error; err != nil {
+		// If article not found
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, failMessage, err)
+		}
+		// If error occurred
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, failMessage, err)
+	}
+
+	// Check if the user is the author of the article or an editor
+	if !utils.CanManageArticle(user, &article) {
+		return utils.SendErrorResponse(ctx, fiber.StatusForbidden, failMessage, errors.New("you are not allowed to change this article"))
+	}
+
+	// Only approved articles wait for a publish time
+	if article.Status != entity.Approved {
+		return utils.SendErrorResponse(ctx, fiber.StatusConflict, failMessage, errors.New("article must be in approved status"))
+	}
+
+	// Update publish time
+	before := utils.AuditSnapshot(article)
+	article.PublishAt = publishAt
+	if err := database.DB.Model(&article).Update("publish_at", publishAt).Error; err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, failMessage, err)
+	}
+	utils.AuditChange(ctx, action, "article", article.ID, before, article)
+
+	// Keep search index up to date
+	utils.SyncSearchIndex(article.ID)
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, successMessage, fiber.Map{
+		"article": article,
+	})
+}
+
+// changeArticleStatus moves the article identified by the slug parameter from one status to another.
+// Review decisions need the review permission and cannot be made on one's own article,
+// every other change can be made by the author or an editor. The change is recorded in the audit log as action.
+func changeArticleStatus(ctx *fiber.Ctx, action string, from, to entity.ArticleStatus, review bool, failMessage, successMessage string, apply ...func(*entity.Article)) error {
+	// Get User
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, failMessage, errors.New("user not found"))
+	}
+
+	// Check if article exists
+	var article entity.Article
+	if err := database.DB.First(&article, "slug = ?", ctx.Params("slug")).Error; err != nil {
+		// If article not found
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, failMessage, err)
+		}
+		// If error occurred
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, failMessage, err)
+	}
+
+	// Check who is allowed to make the change
+	if review {
+		if !utils.HasPermission(user, utils.ReviewArticles) {
+			return utils.SendErrorResponse(ctx, fiber.StatusForbidden, failMessage, errors.New("you are not allowed to review articles"))
+		}
+		if article.AuthorID == user.ID {
+			return utils.SendErrorResponse(ctx, fiber.StatusForbidden, failMessage, errors.New("you cannot review your own article"))
+		}
+	} else if !utils.CanManageArticle(user, &article) {
+		return utils.SendErrorResponse(ctx, fiber.StatusForbidden, failMessage, errors.New("you are not allowed to change this article"))
+	}
+
+	// Check current status
+	if article.Status != from {
+		return utils.SendErrorResponse(ctx, fiber.StatusConflict, failMessage, errors.New("article must be in "+string(from)+" status"))
+	}
+
+	// Change status
+	before := utils.AuditSnapshot(article)
+	if err := utils.TransitionArticle(&article, to); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusConflict, failMessage, err)
+	}
+	for _, fn := range apply {
+		fn(&article)
+	}
+
+	if err := database.DB.Save(&article).Error; err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, failMessage, err)
+	}
+	utils.AuditChange(ctx, action, "article", article.ID, before, article)
+
+	// Keep search index up to date
+	utils.SyncSearchIndex(article.ID)
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, successMessage, fiber.Map{
+		"article": article,
+	})
+}
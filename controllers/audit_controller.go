@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"go-news-api/models/request"
+	"go-news-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAuditEvents godoc
+// @Summary Get audit log
+// @Description Retrieves a page of the audit log, newest first. Each event records who did what to which resource, with the resource before and after for changes, and the IP address and user agent of the request. Requires the audit:read permission.
+// @Tags Audit
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Events per page (max 100)"
+// @Param cursor query string false "Cursor from a previous page, takes precedence over page"
+// @Param order query string false "Sort order, asc or desc"
+// @Param actor_id query int false "Actor user ID"
+// @Param action query string false "Action, like article_delete or login"
+// @Param outcome query string false "Outcome, like success or wrong_password"
+// @Param target_type query string false "Target type, like article or category"
+// @Param target_id query string false "Target ID"
+// @Param from query string false "Created on or after (YYYY-MM-DD)"
+// @Param to query string false "Created on or before (YYYY-MM-DD)"
+// @Router /audit [get]
+func GetAuditEvents(ctx *fiber.Ctx) error {
+	request := new(request.AuditQueryRequest)
+
+	// Parse query parameters
+	if err := ctx.QueryParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch audit log", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch audit log", err)
+	}
+
+	// Fetch events
+	query := database.DB.Model(&entity.AuditEvent{}).Scopes(utils.FilterAuditEvents(request))
+	events, pagination, err := utils.AuditEventPaginator.Paginate(ctx, query, request.PageRequest)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, utils.ErrInvalidQuery) {
+			status = fiber.StatusBadRequest
+		}
+		return utils.SendErrorResponse(ctx, status, "Failed to fetch audit log", err)
+	}
+
+	return utils.SendPaginatedResponse(ctx, fiber.StatusOK, "Successfully fetched audit log", fiber.Map{
+		"events":       events,
+		"total_events": pagination.Total,
+	}, pagination)
+}
@@ -2,20 +2,22 @@ package controllers
 
 import (
 	"errors"
+	"fmt"
 	"go-news-api/database"
 	"go-news-api/models/entity"
 	"go-news-api/models/request"
 	"go-news-api/utils"
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 )
 
 // Register godoc
 // @Summary Register a new user
-// @Description Registers a new user with name, email, and password.
+// @Description Registers a new user with name, email, and password, and emails a verification code. An email that is already registered gets the same response, and its owner is told about the attempt by email instead.
 // @Tags Auth
 // @Accept  multipart/form-data
 // @Produce  json
@@ -25,6 +27,9 @@ import (
 // @Param password_confirmation formData string true "User Password Confirmation"
 // @Router /register [post]
 func Register(ctx *fiber.Ctx) error {
+	defer utils.EqualizeResponseTime(time.Now())
+
+	const message = "Check your email to finish registering"
 	request := new(request.RegisterRequest)
 
 	// Parse request body
@@ -36,6 +41,9 @@ func Register(ctx *fiber.Ctx) error {
 	if err := utils.Validate.Struct(request); err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to register", err)
 	}
+	if utils.IsReservedEmail(request.Email) {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to register", utils.ErrReservedEmail)
+	}
 
 	// Create user
 	user := entity.User{
@@ -50,17 +58,56 @@ func Register(ctx *fiber.Ctx) error {
 	}
 	user.Password = hashedPassword
 
+	// Check if email is already registered
+	var existing entity.User
+	if err := database.DB.Where("email = ?", request.Email).First(&existing).Error; err == nil {
+		notifyRegistrationAttempt(ctx, &existing)
+		return utils.SendSuccessResponse(ctx, fiber.StatusCreated, message)
+	} else if err != gorm.ErrRecordNotFound {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to register", err)
+	}
+
 	// Save user
 	if err := database.DB.Create(&user).Error; err != nil {
+		// The email may have been registered in the meantime
+		if database.DB.Where("email = ?", request.Email).First(&existing).Error == nil {
+			notifyRegistrationAttempt(ctx, &existing)
+			return utils.SendSuccessResponse(ctx, fiber.StatusCreated, message)
+		}
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to register", err)
 	}
 
-	return utils.SendSuccessResponse(ctx, fiber.StatusCreated, "Sucessfully registered")
+	utils.Audit(ctx, "register", "success", user.ID, user.Email)
+
+	// Send verification email, the user can request another one if this fails
+	otp, err := utils.IssueOtp(user.ID, entity.EmailVerification)
+	if err != nil {
+		fmt.Printf("Error issuing verification code for user %d: %v\n", user.ID, err)
+		return utils.SendSuccessResponse(ctx, fiber.StatusCreated, message)
+	}
+	record := utils.NewAuditRecord(ctx, "email_verification_request", "sent", user.ID, user.Email)
+	utils.WriteAudit(record)
+	sendAuditedEmail(record, user.Email, "Verify your email", "views/emails/verification.html", fiber.Map{
+		"name": user.Name,
+		"otp":  otp,
+	})
+
+	return utils.SendSuccessResponse(ctx, fiber.StatusCreated, message)
+}
+
+// notifyRegistrationAttempt tells the owner of an email that someone tried to register with it,
+// rather than telling the requester that the email is taken.
+func notifyRegistrationAttempt(ctx *fiber.Ctx, user *entity.User) {
+	record := utils.NewAuditRecord(ctx, "register", "email_taken", user.ID, user.Email)
+	utils.WriteAudit(record)
+	sendAuditedEmail(record, user.Email, "Someone tried to register with your email", "views/emails/registration_attempt.html", fiber.Map{
+		"name": user.Name,
+	})
 }
 
 // Login godoc
 // @Summary User login
-// @Description Logs in a user with email and password, and returns a JWT token.
+// @Description Logs in a user with email and password, and returns a short-lived JWT access token and a refresh token. Users with two-factor authentication get a challenge token to exchange at /login/2fa instead. Unknown emails and wrong passwords get the same response. Repeated failures per account and per IP address back off exponentially, and too many lock the account until it is unlocked from the emailed link.
 // @Tags Auth
 // @Accept  multipart/form-data
 // @Produce  json
@@ -68,6 +115,8 @@ func Register(ctx *fiber.Ctx) error {
 // @Param password formData string true "User Password"
 // @Router /login [post]
 func Login(ctx *fiber.Ctx) error {
+	defer utils.EqualizeResponseTime(time.Now())
+
 	request := new(request.LoginRequest)
 
 	// Parse request body
@@ -80,44 +129,259 @@ func Login(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to login", err)
 	}
 
+	// Refuse while the account or IP address is backing off or locked
+	if err := utils.CheckLoginAllowed(request.Email, ctx.IP()); err != nil {
+		return sendLoginThrottled(ctx, request.Email, err)
+	}
+
 	// Find user
 	var user entity.User
 	if err := database.DB.Where("email = ?", request.Email).First(&user).Error; err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to login", err)
+		if err != gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to login", err)
+		}
+
+		// Spend the same work as a real password check
+		utils.VerifyDummyPassword(request.Password)
+		utils.Audit(ctx, "login", "unknown_email", 0, request.Email)
+		recordLoginFailure(ctx, request.Email, nil)
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to login", utils.ErrInvalidCredentials)
 	}
 
 	// Check password
 	if err := utils.VerifyPassword(request.Password, user.Password); err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to login", err)
+		utils.Audit(ctx, "login", "wrong_password", user.ID, user.Email)
+		recordLoginFailure(ctx, user.Email, &user)
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to login", utils.ErrInvalidCredentials)
 	}
 
-	// Generate JWT token
-	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"iat":     time.Now().Unix(),
-		"exp":     time.Now().Add(time.Hour * 72).Unix(),
+	return completeLogin(ctx, &user)
+}
+
+// completeLogin finishes a login whose first factor has been checked. Users with two-factor authentication
+// get a challenge token to exchange at /login/2fa, everyone else gets their tokens.
+func completeLogin(ctx *fiber.Ctx, user *entity.User) error {
+	// Ask for the second factor before issuing tokens
+	if user.TwoFactorEnabled {
+		challengeToken, err := utils.GenerateTwoFactorChallenge(user)
+		if err != nil {
+			return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to login", err)
+		}
+
+		utils.Audit(ctx, "login", "two_factor_required", user.ID, user.Email)
+		return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Two-factor authentication required", fiber.Map{
+			"two_factor_required": true,
+			"challenge_token":     challengeToken,
+		})
+	}
+
+	utils.Audit(ctx, "login", "success", user.ID, user.Email)
+	return sendLoginTokens(ctx, user)
+}
+
+// sendLoginThrottled responds to a login refused because of earlier failures.
+func sendLoginThrottled(ctx *fiber.Ctx, email string, err error) error {
+	var throttled *utils.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to login", err)
 	}
 
-	token, err := utils.GenerateToken(&claims)
+	ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	if throttled.Locked {
+		utils.Audit(ctx, "login", "locked", 0, email)
+		return utils.SendErrorResponse(ctx, fiber.StatusLocked, "Failed to login", err)
+	}
+
+	utils.Audit(ctx, "login", "throttled", 0, email)
+	return utils.SendErrorResponse(ctx, fiber.StatusTooManyRequests, "Failed to login", err)
+}
+
+// recordLoginFailure counts a failed login, and emails an unlock token when the failure locks an existing account.
+func recordLoginFailure(ctx *fiber.Ctx, email string, user *entity.User) {
+	unlockToken, err := utils.RecordLoginFailure(email, ctx.IP())
 	if err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to login", err)
+		fmt.Printf("Error recording failed login: %v\n", err)
+		return
+	}
+	if unlockToken == "" || user == nil {
+		return
+	}
+
+	record := utils.NewAuditRecord(ctx, "account_lockout", "locked", user.ID, user.Email)
+	utils.WriteAudit(record)
+	sendAuditedEmail(record, user.Email, "Your account has been locked", "views/emails/account_locked.html", fiber.Map{
+		"name":  user.Name,
+		"token": unlockToken,
+	})
+}
+
+// sendLoginTokens starts a session for the user and responds with its tokens.
+func sendLoginTokens(ctx *fiber.Ctx, user *entity.User) error {
+	// Forget earlier failures of the account
+	if err := utils.ResetLoginFailures(user.Email); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to login", err)
+	}
+
+	_, tokens, err := utils.CreateSession(user, ctx.Get(fiber.HeaderUserAgent), ctx.IP())
+	if err != nil {
+		if errors.Is(err, utils.ErrSystemAccount) {
+			return utils.SendErrorResponse(ctx, fiber.StatusForbidden, "Failed to login", err)
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to login", err)
 	}
 
 	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully logged in", fiber.Map{
-		"token": token,
-		"user":  user,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
+	})
+}
+
+// sendAuditedEmail sends the email in the background, so its latency does not tell whether the account exists,
+// and records a failure in the audit log.
+func sendAuditedEmail(record utils.AuditRecord, to, subject, templateFile string, data fiber.Map) {
+	go func() {
+		if err := utils.SendEmail(to, subject, templateFile, data); err != nil {
+			record.Outcome = "email_failed"
+			record.Time = time.Now()
+			utils.WriteAudit(record)
+		}
+	}()
+}
+
+// UnlockAccount godoc
+// @Summary Unlock account
+// @Description Lifts a login lockout with the unlock token sent by email when the account was locked.
+// @Tags Auth
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param email formData string true "User Email"
+// @Param token formData string true "Unlock Token"
+// @Router /login/unlock [post]
+func UnlockAccount(ctx *fiber.Ctx) error {
+	request := new(request.UnlockAccountRequest)
+
+	// Parse request body
+	if err := ctx.BodyParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to unlock account", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to unlock account", err)
+	}
+
+	// Lift lockout
+	if err := utils.UnlockAccount(request.Email, request.Token); err != nil {
+		if errors.Is(err, utils.ErrInvalidUnlockToken) {
+			utils.Audit(ctx, "account_unlock", "invalid_token", 0, request.Email)
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to unlock account", err)
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to unlock account", err)
+	}
+
+	utils.Audit(ctx, "account_unlock", "success", 0, request.Email)
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Account has been unlocked")
+}
+
+// RefreshToken godoc
+// @Summary Refresh access token
+// @Description Exchanges a refresh token for a new access token and refresh token. Each refresh token can only be used once; using it again revokes the whole session.
+// @Tags Auth
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param refresh_token formData string true "Refresh Token"
+// @Router /token/refresh [post]
+func RefreshToken(ctx *fiber.Ctx) error {
+	request := new(request.RefreshTokenRequest)
+
+	// Parse request body
+	if err := ctx.BodyParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to refresh token", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to refresh token", err)
+	}
+
+	// Rotate refresh token
+	_, tokens, err := utils.RefreshSession(request.RefreshToken)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidRefreshToken) || errors.Is(err, utils.ErrRefreshTokenReused) {
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to refresh token", err)
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to refresh token", err)
+	}
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully refreshed token", fiber.Map{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 	})
 }
 
+// Logout godoc
+// @Summary Logout
+// @Description Revokes the current session, so its access and refresh tokens stop working.
+// @Tags Auth
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Router /logout [post]
+func Logout(ctx *fiber.Ctx) error {
+	// Get session from context
+	session := ctx.Locals("session").(*entity.Session)
+	if session == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to logout", errors.New("session not found"))
+	}
+
+	// Revoke session
+	if err := utils.RevokeSession(session.ID); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to logout", err)
+	}
+
+	utils.AuditChange(ctx, "logout", "session", session.ID, session, nil)
+
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully logged out")
+}
+
+// LogoutAll godoc
+// @Summary Logout from all devices
+// @Description Revokes every session of the authenticated user, including the current one.
+// @Tags Auth
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Router /logout-all [post]
+func LogoutAll(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to logout", errors.New("user not found"))
+	}
+
+	// Revoke all sessions
+	if err := utils.RevokeUserSessions(database.DB, user.ID); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to logout", err)
+	}
+
+	utils.Audit(ctx, "logout_all", "success", user.ID, user.Email)
+
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully logged out from all sessions")
+}
+
 // SendVerificationEmail godoc
 // @Summary Send verification email
-// @Description Sends a verification email to the user with a generated OTP if the email is not already verified.
+// @Description Sends a verification email to the user with a generated OTP if the email is not already verified. A new code can only be requested after the resend cooldown. The response is the same whether or not the email is registered.
 // @Tags Auth
 // @Accept  multipart/form-data
 // @Produce  json
 // @Param email formData string true "User Email"
 // @Router /email-verification/request [post]
 func SendVerificationEmail(ctx *fiber.Ctx) error {
+	defer utils.EqualizeResponseTime(time.Now())
+
+	const message = "If the email is registered and not yet verified, a verification email has been sent"
 	request := new(request.SendVerificationEmailRequest)
 
 	// Parse request body
@@ -134,64 +398,42 @@ func SendVerificationEmail(ctx *fiber.Ctx) error {
 	var user entity.User
 	if err := database.DB.Where("email = ?", request.Email).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to send verification email", err)
+			utils.Audit(ctx, "email_verification_request", "unknown_email", 0, request.Email)
+			return utils.SendSuccessResponse(ctx, fiber.StatusOK, message)
 		}
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send verification email", err)
 	}
 
 	// Check if email is already verified
 	if user.IsVerified {
-		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to send verification email", errors.New("email is already verified"))
+		utils.Audit(ctx, "email_verification_request", "already_verified", user.ID, user.Email)
+		return utils.SendSuccessResponse(ctx, fiber.StatusOK, message)
 	}
 
 	// Generate OTP
-	otp := utils.GenerateOTP(4)
-	otpExpiredAt := time.Now().Add(time.Minute * 10)
-
-	// Check if OTP already exists for the user
-	var existingOtp entity.OtpCode
-	err := database.DB.Where("user_id = ? AND type = ?", user.ID, entity.EmailVerification).First(&existingOtp).Error
-
-	if err != nil && err != gorm.ErrRecordNotFound {
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send verification email", err)
-	}
-
-	if err == gorm.ErrRecordNotFound {
-		// Create new OTP
-		otpCode := entity.OtpCode{
-			Otp:       otp,
-			ExpiredAt: otpExpiredAt,
-			Type:      entity.EmailVerification,
-			UserID:    user.ID,
-		}
-
-		if err := database.DB.Create(&otpCode).Error; err != nil {
-			return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send verification email", err)
-		}
-	} else {
-		// Update existing OTP
-		existingOtp.Otp = otp
-		existingOtp.ExpiredAt = otpExpiredAt
-
-		if err := database.DB.Save(&existingOtp).Error; err != nil {
-			return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send verification email", err)
+	otp, err := utils.IssueOtp(user.ID, entity.EmailVerification)
+	if err != nil {
+		if errors.Is(err, utils.ErrOtpCooldown) || errors.Is(err, utils.ErrOtpLocked) {
+			utils.Audit(ctx, "email_verification_request", otpOutcome(err), user.ID, user.Email)
+			return utils.SendSuccessResponse(ctx, fiber.StatusOK, message)
 		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send verification email", err)
 	}
 
 	// Send email
-	if err := utils.SendEmail(user.Email, "Verify your email", "views/emails/verification.html", fiber.Map{
+	record := utils.NewAuditRecord(ctx, "email_verification_request", "sent", user.ID, user.Email)
+	utils.WriteAudit(record)
+	sendAuditedEmail(record, user.Email, "Verify your email", "views/emails/verification.html", fiber.Map{
 		"name": user.Name,
 		"otp":  otp,
-	}); err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send verification email", err)
-	}
+	})
 
-	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully sent verification email")
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, message)
 }
 
 // VerifyEmail godoc
 // @Summary Verify email
-// @Description Verifies a user's email address using an OTP code sent to the email. The email must be provided, and the OTP code must match and be valid.
+// @Description Verifies a user's email address using an OTP code sent to the email. The email must be provided, and the OTP code must match and be valid. Each code can be used once and stops working after too many wrong attempts. Unknown emails get the same response as a wrong code.
 // @Tags Auth
 // @Accept  multipart/form-data
 // @Produce  json
@@ -199,6 +441,8 @@ func SendVerificationEmail(ctx *fiber.Ctx) error {
 // @Param otp formData string true "OTP Code"
 // @Router /email-verification/verify [post]
 func VerifyEmail(ctx *fiber.Ctx) error {
+	defer utils.EqualizeResponseTime(time.Now())
+
 	request := new(request.VerifyEmailRequest)
 
 	// Parse request body
@@ -206,54 +450,47 @@ func VerifyEmail(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to verify email", err)
 	}
 
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to verify email", err)
+	}
+
 	// Check if user exists
 	var user entity.User
 	err := database.DB.Where("email = ?", request.Email).First(&user).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to verify email", err)
+			utils.Audit(ctx, "email_verification", "unknown_email", 0, request.Email)
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to verify email", utils.ErrOtpInvalid)
 		}
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to verify email", err)
 	}
 
 	// Check if email is already verified
 	if user.IsVerified {
-		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to verify email", errors.New("email is already verified"))
+		utils.Audit(ctx, "email_verification", "already_verified", user.ID, user.Email)
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to verify email", utils.ErrOtpInvalid)
 	}
 
-	// Check if OTP is valid
-	var otpCode entity.OtpCode
-	err = database.DB.Where("user_id = ? AND type = ?", user.ID, entity.EmailVerification).First(&otpCode).Error
+	// Use up OTP and update user's email verification status
+	err = utils.ConsumeOtp(user.ID, entity.EmailVerification, request.Otp, func(tx *gorm.DB) error {
+		return tx.Model(&user).Update("is_verified", true).Error
+	})
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to verify email", errors.New("invalid or expired OTP code"))
+		if errors.Is(err, utils.ErrOtpInvalid) || errors.Is(err, utils.ErrOtpLocked) {
+			utils.Audit(ctx, "email_verification", otpOutcome(err), user.ID, user.Email)
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to verify email", utils.ErrOtpInvalid)
 		}
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to verify email", err)
 	}
 
-	if otpCode.Otp != request.Otp || time.Now().After(otpCode.ExpiredAt) {
-		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to verify email", errors.New("invalid or expired OTP code"))
-	}
-
-	// Update user's email verification status
-	err = database.DB.Model(&user).Updates(map[string]interface{}{
-		"is_verified": true,
-	}).Error
-	if err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to verify email", err)
-	}
-
-	// Remove OTP code after successful verification
-	if err := database.DB.Delete(&otpCode).Error; err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to verify email", err)
-	}
-
+	utils.Audit(ctx, "email_verification", "success", user.ID, user.Email)
 	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Email has been verified")
 }
 
 // GetProfile godoc
 // @Summary Get user profile
-// @Description Retrieves the profile information of the currently authenticated user. Requires a valid Bearer token in the Authorization header.
+// @Description Retrieves the profile information of the currently authenticated user, including a pending email change and a scheduled account deletion that other users cannot see. Requires a valid Bearer token in the Authorization header.
 // @Tags Auth
 // @Accept  json
 // @Produce  json
@@ -267,18 +504,21 @@ func GetProfile(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized", errors.New("user not found"))
 	}
 
-	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully get profile", fiber.Map{"user": user})
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully get profile", fiber.Map{"user": user.Profile()})
 }
 
 // SendResetPasswordEmail godoc
 // @Summary Send reset password email
-// @Description Sends a reset password email to the user with a one-time password (OTP). Requires the user's email address in the request body.
+// @Description Sends a reset password email to the user with a one-time password (OTP). Requires the user's email address in the request body. A new code can only be requested after the resend cooldown. The response is the same whether or not the email is registered.
 // @Tags Auth
 // @Accept  multipart/form-data
 // @Produce  json
 // @Param email formData string true "User's email address"
 // @Router /reset-password/request [post]
 func SendResetPasswordEmail(ctx *fiber.Ctx) error {
+	defer utils.EqualizeResponseTime(time.Now())
+
+	const message = "If the email is registered, a reset password email has been sent"
 	request := new(request.SendResetPasswordEmailRequest)
 
 	// Parse request body
@@ -295,59 +535,36 @@ func SendResetPasswordEmail(ctx *fiber.Ctx) error {
 	var user entity.User
 	if err := database.DB.Where("email = ?", request.Email).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to send reset password email", err)
+			utils.Audit(ctx, "password_reset_request", "unknown_email", 0, request.Email)
+			return utils.SendSuccessResponse(ctx, fiber.StatusOK, message)
 		}
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send reset password email", err)
 	}
 
 	// Generate OTP
-	otp := utils.GenerateOTP(4)
-	otpExpiredAt := time.Now().Add(time.Minute * 10)
-
-	// Check if OTP already exists for the user
-	var existingOtp entity.OtpCode
-	err := database.DB.Where("user_id = ? AND type = ?", user.ID, entity.PasswordReset).First(&existingOtp).Error
-
-	if err != nil && err != gorm.ErrRecordNotFound {
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send reset password email", err)
-	}
-
-	if err == gorm.ErrRecordNotFound {
-		// Create new OTP
-		otpCode := entity.OtpCode{
-			Otp:       otp,
-			ExpiredAt: otpExpiredAt,
-			Type:      entity.PasswordReset,
-			UserID:    user.ID,
-		}
-
-		if err := database.DB.Create(&otpCode).Error; err != nil {
-			return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send reset password email", err)
-		}
-	} else {
-		// Update existing OTP
-		existingOtp.Otp = otp
-		existingOtp.ExpiredAt = otpExpiredAt
-
-		if err := database.DB.Save(&existingOtp).Error; err != nil {
-			return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send reset password email", err)
+	otp, err := utils.IssueOtp(user.ID, entity.PasswordReset)
+	if err != nil {
+		if errors.Is(err, utils.ErrOtpCooldown) || errors.Is(err, utils.ErrOtpLocked) {
+			utils.Audit(ctx, "password_reset_request", otpOutcome(err), user.ID, user.Email)
+			return utils.SendSuccessResponse(ctx, fiber.StatusOK, message)
 		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send reset password email", err)
 	}
 
 	// Send email
-	if err := utils.SendEmail(user.Email, "Reset your password", "views/emails/reset_password.html", fiber.Map{
+	record := utils.NewAuditRecord(ctx, "password_reset_request", "sent", user.ID, user.Email)
+	utils.WriteAudit(record)
+	sendAuditedEmail(record, user.Email, "Reset your password", "views/emails/reset_password.html", fiber.Map{
 		"name": user.Name,
 		"otp":  otp,
-	}); err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send reset password email", err)
-	}
+	})
 
-	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully sent reset password email")
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, message)
 }
 
 // VerifyOtpReset godoc
 // @Summary Verify OTP for password reset
-// @Description Verifies the OTP sent for resetting the password. Requires the user's email and OTP in the request body.
+// @Description Verifies the OTP sent for resetting the password and returns a short-lived, single-use reset token for /reset-password. Requires the user's email and OTP in the request body. The code stops working after too many wrong attempts. Unknown emails get the same response as a wrong code.
 // @Tags Auth
 // @Accept  multipart/form-data
 // @Produce  json
@@ -355,6 +572,8 @@ func SendResetPasswordEmail(ctx *fiber.Ctx) error {
 // @Param otp formData string true "One-time password (OTP)"
 // @Router /reset-password/verify [post]
 func VerifyOtpReset(ctx *fiber.Ctx) error {
+	defer utils.EqualizeResponseTime(time.Now())
+
 	request := new(request.VerifyOtpResetRequest)
 
 	// Parse request body
@@ -371,52 +590,43 @@ func VerifyOtpReset(ctx *fiber.Ctx) error {
 	var user entity.User
 	if err := database.DB.Where("email = ?", request.Email).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to verify OTP", err)
+			utils.Audit(ctx, "password_reset_verify", "unknown_email", 0, request.Email)
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to verify OTP", utils.ErrOtpInvalid)
 		}
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to verify OTP", err)
 	}
 
-	// Find OTP
-	var otpCode entity.OtpCode
-	if err := database.DB.Where("user_id = ? AND type = ?", user.ID, entity.PasswordReset).First(&otpCode).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to verify OTP", err)
-		}
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to verify OTP", err)
-	}
-
-	// Check if OTP is already verified
-	if otpCode.IsVerified {
-		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to verify OTP", errors.New("otp is already verified"))
-	}
-
-	// Check if OTP is valid
-	if otpCode.Otp != request.Otp || time.Now().After(otpCode.ExpiredAt) {
-		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to verify OTP", errors.New("invalid or expired OTP code"))
-	}
-
-	// Set OTP to verified
-	err := database.DB.Model(&otpCode).Updates(map[string]interface{}{
-		"is_verified": true,
-	}).Error
+	// Exchange OTP for a reset token
+	resetToken, err := utils.IssuePasswordResetToken(user.ID, request.Otp)
 	if err != nil {
+		if errors.Is(err, utils.ErrOtpInvalid) || errors.Is(err, utils.ErrOtpLocked) {
+			utils.Audit(ctx, "password_reset_verify", otpOutcome(err), user.ID, user.Email)
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to verify OTP", utils.ErrOtpInvalid)
+		}
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to verify OTP", err)
 	}
 
-	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully verified OTP")
+	utils.Audit(ctx, "password_reset_verify", "success", user.ID, user.Email)
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully verified OTP", fiber.Map{
+		"reset_token": resetToken,
+		"expires_in":  int64(utils.PasswordResetTokenTTL().Seconds()),
+	})
 }
 
 // ResetPassword godoc
 // @Summary Reset password
-// @Description Resets the user's password using the OTP sent for password reset. Requires the user's email, OTP, and new password in the request body.
+// @Description Resets the user's password using the reset token returned by /reset-password/verify. The token works once, and all existing sessions of the user are signed out.
 // @Tags Auth
 // @Accept  multipart/form-data
 // @Produce  json
 // @Param email formData string true "User's email address"
+// @Param reset_token formData string true "Reset token"
 // @Param new_password formData string true "New password"
 // @Param new_password_confirmation formData string true "New password confirmation"
 // @Router /reset-password [post]
 func ResetPassword(ctx *fiber.Ctx) error {
+	defer utils.EqualizeResponseTime(time.Now())
+
 	request := new(request.ResetPasswordRequest)
 
 	// Parse request body
@@ -429,40 +639,49 @@ func ResetPassword(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to reset password", err)
 	}
 
+	// Hash password before the lookup, so unknown emails take as long as known ones
+	newHashedPassword, err := utils.HashPassword(request.NewPassword)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to reset password", err)
+	}
+
 	// Find user
 	var user entity.User
 	if err := database.DB.Where("email = ?", request.Email).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to reset password", err)
+			utils.Audit(ctx, "password_reset", "unknown_email", 0, request.Email)
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to reset password", utils.ErrInvalidResetToken)
 		}
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to reset password", err)
 	}
 
-	// Find OTP
-	var otpCode entity.OtpCode
-	if err := database.DB.Where("user_id = ? AND type = ?", user.ID, entity.PasswordReset).First(&otpCode).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to reset password", err)
+	// Use up reset token, update password and sign out everywhere
+	if err := utils.ResetPasswordWithToken(user.ID, request.ResetToken, newHashedPassword); err != nil {
+		if errors.Is(err, utils.ErrInvalidResetToken) {
+			utils.Audit(ctx, "password_reset", "invalid_token", user.ID, user.Email)
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to reset password", err)
 		}
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to reset password", err)
 	}
 
-	// Check if OTP is already verified
-	if !otpCode.IsVerified {
-		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to reset password", errors.New("otp is not verified"))
-	}
+	// Notify user, the password is already changed so a failed email is only audited
+	record := utils.NewAuditRecord(ctx, "password_reset", "success", user.ID, user.Email)
+	utils.WriteAudit(record)
+	sendAuditedEmail(record, user.Email, "Your password was changed", "views/emails/password_changed.html", fiber.Map{
+		"name": user.Name,
+	})
 
-	// Hash password
-	newHashedPassword, err := utils.HashPassword(request.NewPassword)
-	if err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to reset password", err)
-	}
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully reset password")
+}
 
-	// Update password
-	err = database.DB.Model(&user).Update("password", newHashedPassword).Error
-	if err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to reset password", err)
+// otpOutcome names an OTP error for the audit log.
+func otpOutcome(err error) string {
+	switch {
+	case errors.Is(err, utils.ErrOtpLocked):
+		return "locked"
+	case errors.Is(err, utils.ErrOtpCooldown):
+		return "cooldown"
+	default:
+		return "invalid_otp"
 	}
-
-	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully reset password")
 }
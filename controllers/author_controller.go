@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"go-news-api/models/request"
+	"go-news-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetAuthor godoc
+// @Summary Get an author's public profile
+// @Description Retrieves the public profile of a user, with their avatar, bio, social links and number of published articles, along with a page of their published articles. Supports the same pagination and sorting as the article list.
+// @Tags Authors
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Author ID"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Articles per page (max 100)"
+// @Param cursor query string false "Cursor from a previous page, takes precedence over page"
+// @Param sort query string false "Sort by created_at, updated_at, title or popularity"
+// @Param order query string false "Sort order, asc or desc"
+// @Router /authors/{id} [get]
+func GetAuthor(ctx *fiber.Ctx) error {
+	request := new(request.PageRequest)
+
+	// Parse query parameters
+	if err := ctx.QueryParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to get author", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to get author", err)
+	}
+
+	// Find author
+	var author entity.User
+	if err := database.DB.First(&author, "id = ?", ctx.Params("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to get author", errors.New("author not found"))
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get author", err)
+	}
+
+	// Fetch the author's published articles
+	query := database.DB.Model(&entity.Article{}).Scopes(utils.PublicArticles).Where("articles.author_id = ?", author.ID)
+	articles, pagination, err := utils.ArticlePaginator.Paginate(ctx, query, *request)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, utils.ErrInvalidQuery) {
+			status = fiber.StatusBadRequest
+		}
+		return utils.SendErrorResponse(ctx, status, "Failed to get author", err)
+	}
+
+	return utils.SendPaginatedResponse(ctx, fiber.StatusOK, "Successfully get author", fiber.Map{
+		"author": fiber.Map{
+			"id":           author.ID,
+			"name":         author.Name,
+			"avatar":       author.Avatar,
+			"bio":          author.Bio,
+			"social_links": author.SocialLinks,
+			"role":         author.Role,
+			"created_at":   author.CreatedAt,
+		},
+		"article_count": pagination.Total,
+		"articles":      articles,
+	}, pagination)
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"go-news-api/database"
 	"go-news-api/models/entity"
 	"go-news-api/models/request"
@@ -12,23 +13,43 @@ import (
 
 // GetAllCategories godoc
 // @Summary Get all categories
-// @Description Fetches all categories from the database.
+// @Description Fetches a page of categories from the database. Supports page or cursor pagination and sorting.
 // @Tags Categories
 // @Accept  json
 // @Produce  json
+// @Param page query int false "Page number"
+// @Param per_page query int false "Categories per page (max 100)"
+// @Param cursor query string false "Cursor from a previous page, takes precedence over page"
+// @Param sort query string false "Sort by id or name"
+// @Param order query string false "Sort order, asc or desc"
 // @Router /categories [get]
 func GetAllCategories(ctx *fiber.Ctx) error {
-	var categories []entity.Category
+	request := new(request.PageRequest)
 
-	// Fetch all categories
-	if err := database.DB.Find(&categories).Error; err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch categories", err)
+	// Parse query parameters
+	if err := ctx.QueryParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch categories", err)
 	}
 
-	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully fetched categories", fiber.Map{
-		"categories":      categories,
-		"total_categories": len(categories),
-	})
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch categories", err)
+	}
+
+	// Fetch categories
+	categories, pagination, err := utils.CategoryPaginator.Paginate(ctx, database.DB.Model(&entity.Category{}), *request)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, utils.ErrInvalidQuery) {
+			status = fiber.StatusBadRequest
+		}
+		return utils.SendErrorResponse(ctx, status, "Failed to fetch categories", err)
+	}
+
+	return utils.SendPaginatedResponse(ctx, fiber.StatusOK, "Successfully fetched categories", fiber.Map{
+		"categories":       categories,
+		"total_categories": pagination.Total,
+	}, pagination)
 }
 
 // GetCategoryById godoc
@@ -61,10 +82,11 @@ func GetCategoryById(ctx *fiber.Ctx) error {
 
 // CreateCategory godoc
 // @Summary Create category
-// @Description Creates a new category in the database.
+// @Description Creates a new category in the database. Requires the categories:manage permission.
 // @Tags Categories
 // @Accept  multipart/form-data
 // @Produce  json
+// @Param Authorization header string true "Bearer token"
 // @Param name formData string true "Category Name"
 // @Param description formData string true "Category Description"
 // @Router /categories [post]
@@ -90,16 +112,18 @@ func CreateCategory(ctx *fiber.Ctx) error {
 	if err := database.DB.Create(&category).Error; err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create category", err)
 	}
+	utils.AuditChange(ctx, "category_create", "category", category.ID, nil, category)
 
 	return utils.SendSuccessResponse(ctx, fiber.StatusCreated, "Successfully created category")
 }
 
 // UpdateCategory godoc
 // @Summary Update category
-// @Description Updates a category in the database.
+// @Description Updates a category in the database. Requires the categories:manage permission.
 // @Tags Categories
 // @Accept  multipart/form-data
 // @Produce  json
+// @Param Authorization header string true "Bearer token"
 // @Param id path int true "Category ID"
 // @Param name formData string true "Category Name"
 // @Param description formData string true "Category Description"
@@ -130,22 +154,25 @@ func UpdateCategory(ctx *fiber.Ctx) error {
 	}
 
 	// Update category
+	before := utils.AuditSnapshot(category)
 	category.Name = request.Name
 	category.Description = request.Description
 
 	if err := database.DB.Save(&category).Error; err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update category", err)
 	}
+	utils.AuditChange(ctx, "category_update", "category", category.ID, before, category)
 
 	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully updated category")
 }
 
 // DeleteCategory godoc
 // @Summary Delete category
-// @Description Deletes a category from the database.
+// @Description Deletes a category from the database. Requires the categories:manage permission.
 // @Tags Categories
 // @Accept  json
 // @Produce  json
+// @Param Authorization header string true "Bearer token"
 // @Param id path int true "Category ID"
 // @Router /categories/{id} [delete]
 func DeleteCategory(ctx *fiber.Ctx) error {
@@ -162,9 +189,19 @@ func DeleteCategory(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete category", err)
 	}
 
+	// Find the articles deleted with the category
+	var articleIDs []uint
+	if err := database.DB.Model(&entity.Article{}).Where("category_id = ?", category.ID).Pluck("id", &articleIDs).Error; err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete category", err)
+	}
+
 	if err := database.DB.Delete(&category).Error; err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete category", err)
 	}
+	utils.AuditChange(ctx, "category_delete", "category", category.ID, category, nil)
+
+	// Keep search index up to date
+	utils.SyncSearchIndexes(articleIDs)
 
 	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully delete category")
 }
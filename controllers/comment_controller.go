@@ -13,12 +13,13 @@ import (
 
 // CreateComment godoc
 // @Summary Create a comment for an article
-// @Description Creates a new comment for the specified article. Requires user to be authenticated and the article to exist.
+// @Description Creates a new comment for the specified article, or a reply to one of its comments when parent_id is set. Replies can be nested up to COMMENT_MAX_DEPTH levels. Requires user to be authenticated with a verified email, otherwise the error code is email_not_verified, and the article to exist.
 // @Tags Comments
 // @Produce  json
 // @Param Authorization header string true "Bearer token"
 // @Param slug path string true "Article Slug"
 // @Param content formData string true "Comment content"
+// @Param parent_id formData int false "ID of the comment being replied to"
 // @Router /articles/{slug}/comments [post]
 func CreateComment(ctx *fiber.Ctx) error {
 	// Get User
@@ -57,16 +58,34 @@ func CreateComment(ctx *fiber.Ctx) error {
 		Content:   request.Content,
 	}
 
+	// Reply to the parent comment
+	if request.ParentID != nil {
+		parent, err := utils.ReplyParent(article.ID, *request.ParentID)
+		if err != nil {
+			status := fiber.StatusInternalServerError
+			switch {
+			case errors.Is(err, utils.ErrParentCommentNotFound):
+				status = fiber.StatusNotFound
+			case errors.Is(err, utils.ErrParentCommentDeleted), errors.Is(err, utils.ErrCommentTooDeep):
+				status = fiber.StatusBadRequest
+			}
+			return utils.SendErrorResponse(ctx, status, "Failed to create comment", err)
+		}
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+	}
+
 	if err := database.DB.Create(&comment).Error; err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create comment", err)
 	}
+	utils.AuditChange(ctx, "comment_create", "comment", comment.ID, nil, comment)
 
 	return utils.SendSuccessResponse(ctx, fiber.StatusCreated, "Successfully created comment")
 }
 
 // UpdateComment godoc
 // @Summary Update an existing comment
-// @Description Updates the specified comment if the user is the owner. Requires user to be authenticated and the comment to exist.
+// @Description Updates the specified comment if the user is the owner or a moderator. Requires user to be authenticated and the comment to exist.
 // @Tags Comments
 // @Produce  json
 // @Param Authorization header string true "Bearer token"
@@ -92,8 +111,13 @@ func UpdateComment(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update comment", err)
 	}
 
-	// Check if the user is the owner of the comment
-	if comment.UserID != user.ID {
+	// Deleted comments only remain as placeholders for their replies
+	if comment.IsDeleted {
+		return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to update comment", utils.ErrCommentDeleted)
+	}
+
+	// Check if the user is the owner of the comment or a moderator
+	if !utils.CanManageComment(user, &comment) {
 		return utils.SendErrorResponse(ctx, fiber.StatusForbidden, "Failed to update comment", errors.New("you are not allowed to update this comment"))
 	}
 
@@ -109,18 +133,20 @@ func UpdateComment(ctx *fiber.Ctx) error {
 	}
 
 	// Update comment
+	before := utils.AuditSnapshot(comment)
 	comment.Content = request.Content
 
 	if err := database.DB.Save(&comment).Error; err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update comment", err)
 	}
+	utils.AuditChange(ctx, "comment_update", "comment", comment.ID, before, comment)
 
 	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully updated comment")
 }
 
 // DeleteComment godoc
 // @Summary Delete an existing comment
-// @Description Deletes an existing comment. Requires user to be authenticated and authorized to delete the comment.
+// @Description Deletes an existing comment. A comment with replies is replaced by a "[deleted]" placeholder so the thread stays intact. Requires user to be the owner of the comment or a moderator.
 // @Tags Comments
 // @Produce  json
 // @Param Authorization header string true "Bearer token"
@@ -145,15 +171,249 @@ func DeleteComment(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete comment", err)
 	}
 
-	// Check if the user is the owner of the comment
-	if comment.UserID != user.ID {
+	// Deleted comments only remain as placeholders for their replies
+	if comment.IsDeleted {
+		return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to delete comment", utils.ErrCommentDeleted)
+	}
+
+	// Check if the user is the owner of the comment or a moderator
+	if !utils.CanManageComment(user, &comment) {
 		return utils.SendErrorResponse(ctx, fiber.StatusForbidden, "Failed to delete comment", errors.New("you are not allowed to delete this comment"))
 	}
 
 	// Delete comment
-	if err := database.DB.Delete(&comment).Error; err != nil {
+	if err := utils.DeleteComment(&comment); err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete comment", err)
 	}
+	utils.AuditChange(ctx, "comment_delete", "comment", comment.ID, comment, nil)
 
 	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully deleted comment")
 }
+
+// GetArticleComments godoc
+// @Summary Get the comments of an article
+// @Description Retrieves every comment of a published article as a flat list, replies included with their parent_id. Pass next_cursor from the pagination as cursor to fetch the next page. Use the comment tree endpoint to get them nested.
+// @Tags Comments
+// @Produce  json
+// @Param slug path string true "Article Slug"
+// @Param per_page query int false "Comments per page (max 100)"
+// @Param cursor query string false "Cursor from a previous page, takes precedence over page"
+// @Param page query int false "Page number"
+// @Param sort query string false "Sort by newest (default), oldest or top, top being the most replied to"
+// @Router /articles/{slug}/comments [get]
+func GetArticleComments(ctx *fiber.Ctx) error {
+	request := new(request.PageRequest)
+
+	// Parse query parameters
+	if err := ctx.QueryParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch comments", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch comments", err)
+	}
+	if request.Sort == "" {
+		request.Sort = "newest"
+	}
+
+	// Find published article by slug
+	var article entity.Article
+	if err := database.DB.Scopes(utils.PublicArticles).First(&article, "slug = ?", ctx.Params("slug")).Error; err != nil {
+		// If article not found
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to fetch comments", err)
+		}
+		// If error occurred
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch comments", err)
+	}
+
+	// Fetch a page of comments
+	query := database.DB.Model(&entity.Comment{}).Scopes(utils.VisibleComments).Where("comments.article_id = ?", article.ID)
+	comments, pagination, err := utils.CommentPaginator.Paginate(ctx, query, *request)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, utils.ErrInvalidQuery) {
+			status = fiber.StatusBadRequest
+		}
+		return utils.SendErrorResponse(ctx, status, "Failed to fetch comments", err)
+	}
+
+	return utils.SendPaginatedResponse(ctx, fiber.StatusOK, "Successfully fetched comments", fiber.Map{
+		"comments":       comments,
+		"total_comments": pagination.Total,
+	}, pagination)
+}
+
+// GetUserComments godoc
+// @Summary Get the comments of a user
+// @Description Retrieves the comments a user posted on published articles. Pass next_cursor from the pagination as cursor to fetch the next page.
+// @Tags Comments
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param per_page query int false "Comments per page (max 100)"
+// @Param cursor query string false "Cursor from a previous page, takes precedence over page"
+// @Param page query int false "Page number"
+// @Param sort query string false "Sort by newest (default), oldest or top, top being the most replied to"
+// @Router /users/{id}/comments [get]
+func GetUserComments(ctx *fiber.Ctx) error {
+	request := new(request.PageRequest)
+
+	// Parse query parameters
+	if err := ctx.QueryParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch comments", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch comments", err)
+	}
+	if request.Sort == "" {
+		request.Sort = "newest"
+	}
+
+	// Check if user exists
+	var user entity.User
+	if err := database.DB.First(&user, "id = ?", ctx.Params("id")).Error; err != nil {
+		// If user not found
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to fetch comments", errors.New("user not found"))
+		}
+		// If error occurred
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch comments", err)
+	}
+
+	// Fetch a page of the user's comments on published articles
+	publicArticles := database.DB.Model(&entity.Article{}).Select("articles.id").Scopes(utils.PublicArticles)
+	query := database.DB.Model(&entity.Comment{}).
+		Scopes(utils.VisibleComments).
+		Where("comments.user_id = ? AND comments.article_id IN (?)", user.ID, publicArticles)
+	comments, pagination, err := utils.CommentPaginator.Paginate(ctx, query, *request)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, utils.ErrInvalidQuery) {
+			status = fiber.StatusBadRequest
+		}
+		return utils.SendErrorResponse(ctx, status, "Failed to fetch comments", err)
+	}
+
+	return utils.SendPaginatedResponse(ctx, fiber.StatusOK, "Successfully fetched comments", fiber.Map{
+		"comments":       comments,
+		"total_comments": pagination.Total,
+	}, pagination)
+}
+
+// GetCommentTree godoc
+// @Summary Get the comment threads of an article
+// @Description Retrieves the top-level comments of a published article with their replies nested below them. Top-level comments are paginated with page or cursor, and each comment includes at most replies_per_page of its oldest replies together with its reply_count, the rest can be fetched from the replies endpoint.
+// @Tags Comments
+// @Produce  json
+// @Param slug path string true "Article Slug"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Top-level comments per page"
+// @Param cursor query string false "Cursor from a previous page"
+// @Param sort query string false "Sort by oldest (default), newest or top, top being the most replied to"
+// @Param order query string false "Sort order, asc or desc, instead of the one implied by sort"
+// @Param depth query int false "Levels of replies to include, up to COMMENT_MAX_DEPTH"
+// @Param replies_per_page query int false "Replies included for each comment"
+// @Router /articles/{slug}/comments/tree [get]
+func GetCommentTree(ctx *fiber.Ctx) error {
+	// Find published article by slug
+	var article entity.Article
+	if err := database.DB.Scopes(utils.PublicArticles).First(&article, "slug = ?", ctx.Params("slug")).Error; err != nil {
+		// If article not found
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to fetch comments", err)
+		}
+		// If error occurred
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch comments", err)
+	}
+
+	query := database.DB.Model(&entity.Comment{}).Where("comments.article_id = ? AND comments.parent_id IS NULL", article.ID)
+	return sendCommentTree(ctx, query, "Successfully fetched comments")
+}
+
+// GetCommentReplies godoc
+// @Summary Get the replies to a comment
+// @Description Retrieves the direct replies to a comment of a published article, paginated with page or cursor, with their own replies nested the same way as the comment tree.
+// @Tags Comments
+// @Produce  json
+// @Param slug path string true "Article Slug"
+// @Param id path string true "Comment ID"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Replies per page"
+// @Param cursor query string false "Cursor from a previous page"
+// @Param sort query string false "Sort by oldest (default), newest or top, top being the most replied to"
+// @Param order query string false "Sort order, asc or desc, instead of the one implied by sort"
+// @Param depth query int false "Levels of nested replies to include, up to COMMENT_MAX_DEPTH"
+// @Param replies_per_page query int false "Nested replies included for each reply"
+// @Router /articles/{slug}/comments/{id}/replies [get]
+func GetCommentReplies(ctx *fiber.Ctx) error {
+	// Find published article by slug
+	var article entity.Article
+	if err := database.DB.Scopes(utils.PublicArticles).First(&article, "slug = ?", ctx.Params("slug")).Error; err != nil {
+		// If article not found
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to fetch replies", err)
+		}
+		// If error occurred
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch replies", err)
+	}
+
+	// Check if comment exist
+	var comment entity.Comment
+	if err := database.DB.First(&comment, "id = ? AND article_id = ?", ctx.Params("id"), article.ID).Error; err != nil {
+		// If comment not found
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to fetch replies", err)
+		}
+		// If error occurred
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch replies", err)
+	}
+
+	query := database.DB.Model(&entity.Comment{}).Where("comments.parent_id = ?", comment.ID)
+	return sendCommentTree(ctx, query, "Successfully fetched replies")
+}
+
+// sendCommentTree responds with a page of the comments matched by query and their nested replies.
+func sendCommentTree(ctx *fiber.Ctx, query *gorm.DB, message string) error {
+	// Parse query
+	request := new(request.CommentTreeRequest)
+	if err := ctx.QueryParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch comments", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch comments", err)
+	}
+
+	depth := utils.CommentMaxDepth()
+	if request.Depth != nil && *request.Depth < depth {
+		depth = *request.Depth
+	}
+	repliesPerPage := request.RepliesPerPage
+	if repliesPerPage == 0 {
+		repliesPerPage = utils.DefaultRepliesPerPage
+	}
+
+	// Fetch a page of comments
+	comments, pagination, err := utils.CommentPaginator.Paginate(ctx, query, request.PageRequest)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, utils.ErrInvalidQuery) {
+			status = fiber.StatusBadRequest
+		}
+		return utils.SendErrorResponse(ctx, status, "Failed to fetch comments", err)
+	}
+
+	// Fetch their replies
+	if err := utils.LoadReplies(comments, depth, repliesPerPage); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch comments", err)
+	}
+
+	return utils.SendPaginatedResponse(ctx, fiber.StatusOK, message, fiber.Map{
+		"comments":       comments,
+		"total_comments": pagination.Total,
+	}, pagination)
+}
@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"fmt"
+	"go-news-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetJWKS godoc
+// @Summary Get JSON Web Key Set
+// @Description Returns the public keys that verify the JWTs issued by this API, identified by the kid header of each token. Keys are listed here before they start signing and until they retire.
+// @Tags Auth
+// @Produce  json
+// @Router /.well-known/jwks.json [get]
+func GetJWKS(ctx *fiber.Ctx) error {
+	// Get public keys
+	jwks, err := utils.JWKS()
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get signing keys", err)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int(utils.JWKSMaxAge.Seconds())))
+	return ctx.JSON(jwks)
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"go-news-api/models/request"
+	"go-news-api/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetLoginLockouts godoc
+// @Summary Get login lockouts
+// @Description Lists the accounts and IP addresses that are currently backing off or locked because of failed logins. Requires the users:manage permission.
+// @Tags Users
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Router /lockouts [get]
+func GetLoginLockouts(ctx *fiber.Ctx) error {
+	// Get active lockouts
+	lockouts, err := utils.LoginAttempts.Active(time.Now())
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get lockouts", err)
+	}
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully get lockouts", fiber.Map{
+		"lockouts": lockouts,
+	})
+}
+
+// DeleteLoginLockout godoc
+// @Summary Clear a login lockout
+// @Description Clears the failed logins of an account or IP address, lifting any backoff or lockout. Requires the users:manage permission.
+// @Tags Users
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param key query string true "Lockout key, like account:<email> or ip:<address>"
+// @Router /lockouts [delete]
+func DeleteLoginLockout(ctx *fiber.Ctx) error {
+	request := new(request.ClearLockoutRequest)
+
+	// Parse query
+	if err := ctx.QueryParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to clear lockout", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to clear lockout", err)
+	}
+
+	// Clear lockout
+	before, err := utils.LoginAttempts.Get(request.Key)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to clear lockout", err)
+	}
+	if err := utils.LoginAttempts.Delete(request.Key); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to clear lockout", err)
+	}
+	utils.AuditChange(ctx, "login_lockout_clear", "login_attempt", request.Key, before, nil)
+
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully cleared lockout")
+}
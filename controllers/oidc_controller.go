@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"go-news-api/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetOIDCProviders godoc
+// @Summary Get identity providers
+// @Description Lists the external OpenID Connect providers users can sign in with.
+// @Tags Auth
+// @Produce  json
+// @Router /auth/providers [get]
+func GetOIDCProviders(ctx *fiber.Ctx) error {
+	providers := utils.OIDCProviders()
+	if providers == nil {
+		providers = []string{}
+	}
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully get identity providers", fiber.Map{
+		"providers": providers,
+	})
+}
+
+// OIDCLogin godoc
+// @Summary Sign in with an identity provider
+// @Description Redirects to the provider's sign-in page. The authorization request uses PKCE, a state and a nonce, and the provider sends the user back to the callback. A cookie ties the request to this browser, so the callback only completes here.
+// @Tags Auth
+// @Param provider path string true "Provider name"
+// @Router /auth/{provider}/login [get]
+func OIDCLogin(ctx *fiber.Ctx) error {
+	// Start authorization request
+	authURL, binding, err := utils.StartOIDCFlow(ctx.UserContext(), ctx.Params("provider"), nil)
+	if err != nil {
+		if errors.Is(err, utils.ErrUnknownProvider) {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to sign in", err)
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusBadGateway, "Failed to sign in", err)
+	}
+
+	setOIDCBindingCookie(ctx, binding, utils.OAuthStateTTL)
+	return ctx.Redirect(authURL, fiber.StatusFound)
+}
+
+// OIDCCallback godoc
+// @Summary Identity provider callback
+// @Description Completes a sign-in or account link started with the provider in the same browser. Sign-ins return tokens like /login; unknown external accounts are linked to the user with the same verified email, or a new user is created. If that user has not verified their email the sign-in is refused with 409, and they have to link the provider from their profile.
+// @Tags Auth
+// @Produce  json
+// @Param provider path string true "Provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Router /auth/{provider}/callback [get]
+func OIDCCallback(ctx *fiber.Ctx) error {
+	provider := ctx.Params("provider")
+
+	// Check for errors from the provider
+	if providerError := ctx.Query("error"); providerError != "" {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to sign in", errors.New(providerError))
+	}
+
+	// Finish authorization request started by this browser
+	binding := ctx.Cookies(utils.OIDCBindingCookie)
+	setOIDCBindingCookie(ctx, "", -time.Hour)
+	claims, state, err := utils.FinishOIDCFlow(ctx.UserContext(), provider, ctx.Query("code"), ctx.Query("state"), binding)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrUnknownProvider):
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to sign in", err)
+		case errors.Is(err, utils.ErrInvalidOAuthState):
+			return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to sign in", err)
+		default:
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to sign in", err)
+		}
+	}
+
+	// Link to the user who started the request
+	if state.LinkUserID != nil {
+		if err := utils.LinkOIDCIdentity(*state.LinkUserID, state.Provider, claims); err != nil {
+			if errors.Is(err, utils.ErrIdentityLinked) || errors.Is(err, utils.ErrProviderAlreadyUsed) {
+				return utils.SendErrorResponse(ctx, fiber.StatusConflict, "Failed to link identity", err)
+			}
+			return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to link identity", err)
+		}
+
+		utils.Audit(ctx, "identity_link_"+state.Provider, "success", *state.LinkUserID, claims.Email)
+		return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully linked identity")
+	}
+
+	// Sign in
+	user, err := utils.LoginWithOIDC(state.Provider, claims)
+	if err != nil {
+		if errors.Is(err, utils.ErrEmailNotVerified) || errors.Is(err, utils.ErrReservedEmail) {
+			return utils.SendErrorResponse(ctx, fiber.StatusForbidden, "Failed to sign in", err)
+		}
+		if errors.Is(err, utils.ErrUnverifiedAccount) {
+			return utils.SendErrorResponse(ctx, fiber.StatusConflict, "Failed to sign in", err)
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to sign in", err)
+	}
+
+	return completeLogin(ctx, user)
+}
+
+// GetIdentities godoc
+// @Summary Get linked identities
+// @Description Lists the external identity providers linked to the authenticated user.
+// @Tags Auth
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Router /profile/identities [get]
+func GetIdentities(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to get identities", errors.New("user not found"))
+	}
+
+	// Fetch identities
+	var identities []entity.UserIdentity
+	if err := database.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&identities).Error; err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get identities", err)
+	}
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully get identities", fiber.Map{
+		"identities":   identities,
+		"has_password": user.Password != "",
+	})
+}
+
+// LinkIdentity godoc
+// @Summary Link an identity provider
+// @Description Starts linking an external account to the authenticated user. Open the returned authorization URL in the same browser; the response sets a cookie that the provider's callback needs to complete the link, so the URL cannot be handed to someone else.
+// @Tags Auth
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param provider path string true "Provider name"
+// @Router /profile/identities/{provider} [post]
+func LinkIdentity(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to link identity", errors.New("user not found"))
+	}
+
+	// Start authorization request
+	authURL, binding, err := utils.StartOIDCFlow(ctx.UserContext(), ctx.Params("provider"), &user.ID)
+	if err != nil {
+		if errors.Is(err, utils.ErrUnknownProvider) {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to link identity", err)
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusBadGateway, "Failed to link identity", err)
+	}
+
+	setOIDCBindingCookie(ctx, binding, utils.OAuthStateTTL)
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Continue at the identity provider", fiber.Map{
+		"authorization_url": authURL,
+	})
+}
+
+// UnlinkIdentity godoc
+// @Summary Unlink an identity provider
+// @Description Removes a linked external account from the authenticated user. The last provider of a user without a password cannot be unlinked.
+// @Tags Auth
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param provider path string true "Provider name"
+// @Router /profile/identities/{provider} [delete]
+func UnlinkIdentity(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to unlink identity", errors.New("user not found"))
+	}
+
+	// Unlink identity
+	if err := utils.UnlinkOIDCIdentity(user, ctx.Params("provider")); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to unlink identity", err)
+		}
+		if errors.Is(err, utils.ErrLastLoginMethod) {
+			return utils.SendErrorResponse(ctx, fiber.StatusConflict, "Failed to unlink identity", err)
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to unlink identity", err)
+	}
+
+	utils.Audit(ctx, "identity_unlink_"+ctx.Params("provider"), "success", user.ID, user.Email)
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully unlinked identity")
+}
+
+// setOIDCBindingCookie stores the secret binding an authorization request to this browser, or clears it when ttl
+// is negative. It is only sent back to the callback and survives the redirect from the provider.
+func setOIDCBindingCookie(ctx *fiber.Ctx, binding string, ttl time.Duration) {
+	ctx.Cookie(&fiber.Cookie{
+		Name:     utils.OIDCBindingCookie,
+		Value:    binding,
+		Path:     "/api/auth",
+		Expires:  time.Now().Add(ttl),
+		Secure:   ctx.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
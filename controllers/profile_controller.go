@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"go-news-api/models/request"
+	"go-news-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UpdateProfile godoc
+// @Summary Update user profile
+// @Description Updates the name, bio and social links of the currently authenticated user. Fields that are left out are not changed, and social links are replaced as a whole.
+// @Tags Profile
+// @Accept  json
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param request body request.UpdateProfileRequest true "Profile"
+// @Router /profile [patch]
+func UpdateProfile(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to update profile", errors.New("user not found"))
+	}
+
+	request := new(request.UpdateProfileRequest)
+
+	// Parse request body
+	if err := ctx.BodyParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to update profile", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to update profile", err)
+	}
+
+	// Update profile
+	var columns []string
+	if request.Name != nil {
+		user.Name = *request.Name
+		columns = append(columns, "name")
+	}
+	if request.Bio != nil {
+		user.Bio = *request.Bio
+		columns = append(columns, "bio")
+	}
+	if request.SocialLinks != nil {
+		user.SocialLinks = entity.SocialLinks{
+			Website:  request.SocialLinks.Website,
+			Twitter:  request.SocialLinks.Twitter,
+			Github:   request.SocialLinks.Github,
+			Linkedin: request.SocialLinks.Linkedin,
+		}
+		columns = append(columns, "social_links")
+	}
+	if len(columns) > 0 {
+		if err := database.DB.Model(user).Select(columns).Updates(user).Error; err != nil {
+			return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update profile", err)
+		}
+	}
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully updated profile", fiber.Map{"user": user})
+}
+
+// UploadAvatar godoc
+// @Summary Upload avatar
+// @Description Uploads a new avatar image for the currently authenticated user, replacing the previous one.
+// @Tags Profile
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param avatar formData file true "Avatar Image"
+// @Router /profile/avatar [post]
+func UploadAvatar(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to upload avatar", errors.New("user not found"))
+	}
+
+	// Save the avatar file
+	avatarPath, err := utils.SaveImageFile(ctx, "avatar", "./public/uploads/avatars")
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to upload avatar", err)
+	}
+
+	// Update user
+	oldAvatar := user.Avatar
+	if err := database.DB.Model(user).Update("avatar", avatarPath).Error; err != nil {
+		utils.DeleteFile(avatarPath)
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to upload avatar", err)
+	}
+
+	// Delete old avatar
+	if oldAvatar != "" {
+		if err := utils.DeleteFile(oldAvatar); err != nil {
+			fmt.Printf("Error deleting avatar %s: %v\n", oldAvatar, err)
+		}
+	}
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully uploaded avatar", fiber.Map{"user": user})
+}
+
+// DeleteAvatar godoc
+// @Summary Delete avatar
+// @Description Removes the avatar of the currently authenticated user.
+// @Tags Profile
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Router /profile/avatar [delete]
+func DeleteAvatar(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to delete avatar", errors.New("user not found"))
+	}
+
+	if user.Avatar == "" {
+		return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to delete avatar", errors.New("user has no avatar"))
+	}
+
+	// Update user
+	oldAvatar := user.Avatar
+	if err := database.DB.Model(user).Update("avatar", "").Error; err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete avatar", err)
+	}
+
+	// Delete avatar file
+	if err := utils.DeleteFile(oldAvatar); err != nil {
+		fmt.Printf("Error deleting avatar %s: %v\n", oldAvatar, err)
+	}
+
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully deleted avatar")
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Changes the password of the currently authenticated user, who must confirm the current password. Every other session is signed out and a notice is sent by email.
+// @Tags Profile
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param current_password formData string true "Current Password"
+// @Param new_password formData string true "New Password"
+// @Param new_password_confirmation formData string true "New Password Confirmation"
+// @Router /profile/password [post]
+func ChangePassword(ctx *fiber.Ctx) error {
+	// Get user and session from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to change password", errors.New("user not found"))
+	}
+	session := ctx.Locals("session").(*entity.Session)
+
+	request := new(request.ChangePasswordRequest)
+
+	// Parse request body
+	if err := ctx.BodyParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to change password", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to change password", err)
+	}
+
+	// Check current password
+	if user.Password == "" {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to change password", utils.ErrPasswordNotSet)
+	}
+	if err := utils.VerifyPassword(request.CurrentPassword, user.Password); err != nil {
+		utils.Audit(ctx, "password_change", "wrong_password", user.ID, user.Email)
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to change password", utils.ErrWrongPassword)
+	}
+
+	// Hash password
+	hashedPassword, err := utils.HashPassword(request.NewPassword)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to change password", err)
+	}
+
+	// Update password and sign out other sessions
+	if err := utils.ChangePassword(user.ID, session.ID, hashedPassword); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to change password", err)
+	}
+
+	// Notify user
+	record := utils.NewAuditRecord(ctx, "password_change", "success", user.ID, user.Email)
+	utils.WriteAudit(record)
+	sendAuditedEmail(record, user.Email, "Your password was changed", "views/emails/password_changed.html", fiber.Map{
+		"name": user.Name,
+	})
+
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully changed password")
+}
+
+// ChangeEmail godoc
+// @Summary Request an email change
+// @Description Starts changing the email of the currently authenticated user, who must confirm the current password. An OTP code is sent to the new address, and the email only changes once the code is confirmed.
+// @Tags Profile
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param new_email formData string true "New Email"
+// @Param password formData string true "Current Password"
+// @Router /profile/email [post]
+func ChangeEmail(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to change email", errors.New("user not found"))
+	}
+
+	request := new(request.ChangeEmailRequest)
+
+	// Parse request body
+	if err := ctx.BodyParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to change email", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to change email", err)
+	}
+
+	// Check current password
+	if user.Password == "" {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to change email", utils.ErrPasswordNotSet)
+	}
+	if err := utils.VerifyPassword(request.Password, user.Password); err != nil {
+		utils.Audit(ctx, "email_change_request", "wrong_password", user.ID, user.Email)
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to change email", utils.ErrWrongPassword)
+	}
+
+	// Generate OTP for the new email
+	otp, err := utils.RequestEmailChange(user, request.NewEmail)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrSameEmail), errors.Is(err, utils.ErrReservedEmail):
+			return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to change email", err)
+		case errors.Is(err, utils.ErrEmailTaken):
+			return utils.SendErrorResponse(ctx, fiber.StatusConflict, "Failed to change email", err)
+		default:
+			return utils.SendErrorResponse(ctx, utils.OtpErrorStatus(err), "Failed to change email", err)
+		}
+	}
+
+	// Send OTP to the new email
+	record := utils.NewAuditRecord(ctx, "email_change_request", "sent", user.ID, request.NewEmail)
+	utils.WriteAudit(record)
+	sendAuditedEmail(record, request.NewEmail, "Confirm your new email", "views/emails/email_change.html", fiber.Map{
+		"name": user.Name,
+		"otp":  otp,
+	})
+
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "A confirmation code has been sent to the new email")
+}
+
+// ConfirmEmailChange godoc
+// @Summary Confirm an email change
+// @Description Switches the currently authenticated user to the new email with the OTP code sent to it. A notice is sent to the old email.
+// @Tags Profile
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param otp formData string true "OTP Code"
+// @Router /profile/email/verify [post]
+func ConfirmEmailChange(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to change email", errors.New("user not found"))
+	}
+
+	request := new(request.ConfirmEmailChangeRequest)
+
+	// Parse request body
+	if err := ctx.BodyParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to change email", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to change email", err)
+	}
+
+	// Use up OTP and switch email
+	updated, oldEmail, err := utils.ConfirmEmailChange(user.ID, request.Otp)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrNoPendingEmail):
+			return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to change email", err)
+		case errors.Is(err, utils.ErrEmailTaken):
+			return utils.SendErrorResponse(ctx, fiber.StatusConflict, "Failed to change email", err)
+		default:
+			utils.Audit(ctx, "email_change", otpOutcome(err), user.ID, user.Email)
+			return utils.SendErrorResponse(ctx, utils.OtpErrorStatus(err), "Failed to change email", err)
+		}
+	}
+
+	// Notify the old email, the change is already made so a failed email is only audited
+	record := utils.NewAuditRecord(ctx, "email_change", "success", updated.ID, oldEmail)
+	utils.WriteAudit(record)
+	sendAuditedEmail(record, oldEmail, "Your email was changed", "views/emails/email_changed.html", fiber.Map{
+		"name":      updated.Name,
+		"new_email": updated.Email,
+	})
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully changed email", fiber.Map{"user": updated})
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"go-news-api/models/request"
+	"go-news-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SearchArticles godoc
+// @Summary Search articles
+// @Description Searches the title, content and tags of published articles and returns them ranked by relevance with highlighted snippets. Use "quotes" for phrases and a leading - to exclude a term or phrase.
+// @Tags Articles
+// @Produce  json
+// @Param q query string true "Search query"
+// @Param category_id query int false "Category ID"
+// @Param tag query string false "Tag name"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Results per page (max 100)"
+// @Router /articles/search [get]
+func SearchArticles(ctx *fiber.Ctx) error {
+	request := new(request.SearchArticlesRequest)
+
+	// Parse query parameters
+	if err := ctx.QueryParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to search articles", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to search articles", err)
+	}
+
+	page, perPage := request.Page, request.PerPage
+	if page <= 0 {
+		page = 1
+	}
+	if perPage <= 0 {
+		perPage = utils.DefaultPerPage
+	}
+
+	// Search the index
+	query := utils.ParseSearchQuery(request.Q)
+	query.CategoryID = request.CategoryID
+	query.Tag = request.Tag
+	query.Offset = (page - 1) * perPage
+	query.Limit = perPage
+
+	hits, total, err := utils.Search.Search(query)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, utils.ErrInvalidQuery) {
+			status = fiber.StatusBadRequest
+		}
+		return utils.SendErrorResponse(ctx, status, "Failed to search articles", err)
+	}
+
+	// Load the matching articles
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ArticleID)
+	}
+
+	var articles []entity.Article
+	if len(ids) > 0 {
+		if err := database.DB.Preload("Category").
+			Preload("Author").
+			Preload("Tags").
+			Scopes(utils.WithCommentCount).
+			Find(&articles, ids).Error; err != nil {
+			return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to search articles", err)
+		}
+	}
+
+	articlesByID := make(map[uint]entity.Article, len(articles))
+	for _, article := range articles {
+		articlesByID[article.ID] = article
+	}
+
+	// Keep the ranking order of the index
+	results := make([]fiber.Map, 0, len(hits))
+	for _, hit := range hits {
+		article, ok := articlesByID[hit.ArticleID]
+		if !ok {
+			continue
+		}
+		results = append(results, fiber.Map{
+			"article": article,
+			"score":   hit.Score,
+			"highlight": fiber.Map{
+				"title":   utils.HighlightSearch(article.Title, query, 0),
+				"snippet": utils.HighlightSearch(article.Content, query, 200),
+			},
+		})
+	}
+
+	return utils.SendPaginatedResponse(ctx, fiber.StatusOK, "Successfully searched articles", fiber.Map{
+		"results":       results,
+		"total_results": total,
+	}, utils.PagePagination(ctx, page, perPage, total))
+}
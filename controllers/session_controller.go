@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"go-news-api/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetSessions godoc
+// @Summary Get active sessions
+// @Description Retrieves every active session of the authenticated user with its device, IP address, and when it was created and last seen. The session making the request is marked as current.
+// @Tags Sessions
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Router /sessions [get]
+func GetSessions(ctx *fiber.Ctx) error {
+	// Get user and session from context
+	user := ctx.Locals("user").(*entity.User)
+	current := ctx.Locals("session").(*entity.Session)
+	if user == nil || current == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to fetch sessions", errors.New("user not found"))
+	}
+
+	// Fetch active sessions
+	var sessions []entity.Session
+	if err := database.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch sessions", err)
+	}
+
+	result := make([]fiber.Map, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, fiber.Map{
+			"id":           session.ID,
+			"user_agent":   session.UserAgent,
+			"ip_address":   session.IPAddress,
+			"created_at":   session.CreatedAt,
+			"last_seen_at": session.LastSeenAt,
+			"expires_at":   session.ExpiresAt,
+			"current":      session.ID == current.ID,
+		})
+	}
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully fetched sessions", fiber.Map{
+		"sessions":       result,
+		"total_sessions": len(result),
+	})
+}
+
+// DeleteSession godoc
+// @Summary Sign out a session
+// @Description Revokes one of the authenticated user's sessions, signing that device out remotely.
+// @Tags Sessions
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param id path string true "Session ID"
+// @Router /sessions/{id} [delete]
+func DeleteSession(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to sign out session", errors.New("user not found"))
+	}
+
+	// Check if session exists and belongs to the user
+	var session entity.Session
+	if err := database.DB.First(&session, "id = ? AND user_id = ? AND revoked_at IS NULL", ctx.Params("id"), user.ID).Error; err != nil {
+		// If session not found
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to sign out session", err)
+		}
+		// If error occurred
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to sign out session", err)
+	}
+
+	// Revoke session
+	if err := utils.RevokeSession(session.ID); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to sign out session", err)
+	}
+
+	utils.AuditChange(ctx, "session_revoke", "session", session.ID, session, nil)
+
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully signed out session")
+}
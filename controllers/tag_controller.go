@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"go-news-api/database"
 	"go-news-api/models/entity"
 	"go-news-api/models/request"
@@ -12,22 +13,42 @@ import (
 
 // GetAllTags godoc
 // @Summary Get all tags
-// @Description Fetches all tags from the database.
+// @Description Fetches a page of tags from the database. Supports page or cursor pagination and sorting.
 // @Tags Tags
 // @Produce  json
+// @Param page query int false "Page number"
+// @Param per_page query int false "Tags per page (max 100)"
+// @Param cursor query string false "Cursor from a previous page, takes precedence over page"
+// @Param sort query string false "Sort by id or name"
+// @Param order query string false "Sort order, asc or desc"
 // @Router /tags [get]
 func GetAllTags(ctx *fiber.Ctx) error {
-	var tags []entity.Tag
+	request := new(request.PageRequest)
 
-	// Fetch all tags
-	if err := database.DB.Find(&tags).Error; err != nil {
-		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to fetch tags", err)
+	// Parse query parameters
+	if err := ctx.QueryParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch tags", err)
 	}
 
-	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully fetched tags", fiber.Map{
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to fetch tags", err)
+	}
+
+	// Fetch tags
+	tags, pagination, err := utils.TagPaginator.Paginate(ctx, database.DB.Model(&entity.Tag{}), *request)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, utils.ErrInvalidQuery) {
+			status = fiber.StatusBadRequest
+		}
+		return utils.SendErrorResponse(ctx, status, "Failed to fetch tags", err)
+	}
+
+	return utils.SendPaginatedResponse(ctx, fiber.StatusOK, "Successfully fetched tags", fiber.Map{
 		"tags":       tags,
-		"total_tags": len(tags),
-	})
+		"total_tags": pagination.Total,
+	}, pagination)
 }
 
 // GetTagById godoc
@@ -59,10 +80,11 @@ func GetTagById(ctx *fiber.Ctx) error {
 
 // CreateTag godoc
 // @Summary Create a new tag
-// @Description Creates a new tag and saves it to the database.
+// @Description Creates a new tag and saves it to the database. Requires the tags:manage permission.
 // @Tags Tags
 // @Accept  multipart/form-data
 // @Produce  json
+// @Param Authorization header string true "Bearer token"
 // @Param name formData string true "Tag Name"
 // @Router /tags [post]
 func CreateTag(ctx *fiber.Ctx) error {
@@ -86,16 +108,18 @@ func CreateTag(ctx *fiber.Ctx) error {
 	if err := database.DB.Create(&tag).Error; err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create tag", err)
 	}
+	utils.AuditChange(ctx, "tag_create", "tag", tag.ID, nil, tag)
 
 	return utils.SendSuccessResponse(ctx, fiber.StatusCreated, "Successfully created tag")
 }
 
 // UpdateTag godoc
 // @Summary Update an existing tag
-// @Description Updates an existing tag identified by ID and saves changes to the database.
+// @Description Updates an existing tag identified by ID and saves changes to the database. Requires the tags:manage permission.
 // @Tags Tags
 // @Accept  multipart/form-data
 // @Produce  json
+// @Param Authorization header string true "Bearer token"
 // @Param id path int true "Tag ID"
 // @Param name formData string true "Tag Name"
 // @Router /tags/{id} [put]
@@ -124,19 +148,32 @@ func UpdateTag(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to update tag", err)
 	}
 
+	// Find the articles with this tag
+	var articleIDs []uint
+	if err := database.DB.Model(&entity.ArticleTag{}).Where("tag_id = ?", tag.ID).Pluck("article_id", &articleIDs).Error; err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update tag", err)
+	}
+
 	// Update tag
+	before := utils.AuditSnapshot(tag)
 	if err := database.DB.Model(&tag).Updates(request).Error; err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update tag", err)
 	}
+	tag.Name = request.Name
+	utils.AuditChange(ctx, "tag_update", "tag", tag.ID, before, tag)
+
+	// Keep search index up to date
+	utils.SyncSearchIndexes(articleIDs)
 
 	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully updated tag")
 }
 
 // DeleteTag godoc
 // @Summary Delete a tag
-// @Description Deletes a tag identified by ID from the database.
+// @Description Deletes a tag identified by ID from the database. Requires the tags:manage permission.
 // @Tags Tags
 // @Produce  json
+// @Param Authorization header string true "Bearer token"
 // @Param id path int true "Tag ID"
 // @Router /tags/{id} [delete]
 func DeleteTag(ctx *fiber.Ctx) error {
@@ -153,10 +190,20 @@ func DeleteTag(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete tag", err)
 	}
 
+	// Find the articles with this tag
+	var articleIDs []uint
+	if err := database.DB.Model(&entity.ArticleTag{}).Where("tag_id = ?", tag.ID).Pluck("article_id", &articleIDs).Error; err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete tag", err)
+	}
+
 	// Delete tag
 	if err := database.DB.Delete(&tag).Error; err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete tag", err)
 	}
+	utils.AuditChange(ctx, "tag_delete", "tag", tag.ID, tag, nil)
+
+	// Keep search index up to date
+	utils.SyncSearchIndexes(articleIDs)
 
 	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Successfully deleted tag")
 }
@@ -0,0 +1,254 @@
+package controllers
+
+import (
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"go-news-api/models/request"
+	"go-news-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// EnrollTwoFactor godoc
+// @Summary Start two-factor authentication enrollment
+// @Description Generates a new TOTP secret for the authenticated user and returns it with an otpauth:// URI and a QR code PNG. Two-factor authentication is only enabled after the code is confirmed.
+// @Tags Two-Factor Authentication
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Router /2fa/enroll [post]
+func EnrollTwoFactor(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to enroll two-factor authentication", errors.New("user not found"))
+	}
+
+	// Check if two-factor authentication is already enabled
+	if user.TwoFactorEnabled {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to enroll two-factor authentication", errors.New("two-factor authentication is already enabled"))
+	}
+
+	// Generate secret
+	enrollment, err := utils.GenerateTwoFactorSecret(user)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to enroll two-factor authentication", err)
+	}
+
+	// Keep the secret, encrypted, until it is confirmed
+	secret, err := utils.EncryptTwoFactorSecret(user.ID, enrollment.Secret)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to enroll two-factor authentication", err)
+	}
+	if err := database.DB.Model(user).Update("two_factor_secret", secret).Error; err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to enroll two-factor authentication", err)
+	}
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Scan the QR code and confirm with a code from your authenticator app", fiber.Map{
+		"enrollment": enrollment,
+	})
+}
+
+// ConfirmTwoFactor godoc
+// @Summary Confirm two-factor authentication enrollment
+// @Description Enables two-factor authentication after checking a code from the authenticator app, and returns one-time recovery codes. The recovery codes are only shown once.
+// @Tags Two-Factor Authentication
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param code formData string true "TOTP Code"
+// @Router /2fa/confirm [post]
+func ConfirmTwoFactor(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to confirm two-factor authentication", errors.New("user not found"))
+	}
+
+	request := new(request.TwoFactorCodeRequest)
+
+	// Parse request body
+	if err := ctx.BodyParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to confirm two-factor authentication", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to confirm two-factor authentication", err)
+	}
+
+	// Check enrollment state
+	if user.TwoFactorEnabled {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to confirm two-factor authentication", errors.New("two-factor authentication is already enabled"))
+	}
+	if user.TwoFactorSecret == "" {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to confirm two-factor authentication", errors.New("two-factor authentication enrollment has not been started"))
+	}
+
+	// Check code
+	ok, err := utils.UseTOTP(user, request.Code)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to confirm two-factor authentication", err)
+	}
+	if !ok {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to confirm two-factor authentication", errors.New("invalid code"))
+	}
+
+	// Enable two-factor authentication and generate recovery codes
+	var recoveryCodes []string
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("two_factor_enabled", true).Error; err != nil {
+			return err
+		}
+
+		var err error
+		recoveryCodes, err = utils.GenerateRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to confirm two-factor authentication", err)
+	}
+
+	utils.Audit(ctx, "two_factor_enable", "success", user.ID, user.Email)
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Two-factor authentication has been enabled", fiber.Map{
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Description Turns off two-factor authentication and deletes the recovery codes. Requires the current password and a TOTP or recovery code.
+// @Tags Two-Factor Authentication
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param password formData string true "Current Password"
+// @Param code formData string true "TOTP or Recovery Code"
+// @Router /2fa/disable [post]
+func DisableTwoFactor(ctx *fiber.Ctx) error {
+	// Get user from context
+	user := ctx.Locals("user").(*entity.User)
+	if user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to disable two-factor authentication", errors.New("user not found"))
+	}
+
+	request := new(request.DisableTwoFactorRequest)
+
+	// Parse request body
+	if err := ctx.BodyParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to disable two-factor authentication", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to disable two-factor authentication", err)
+	}
+
+	// Check if two-factor authentication is enabled
+	if !user.TwoFactorEnabled {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to disable two-factor authentication", errors.New("two-factor authentication is not enabled"))
+	}
+
+	// Check password
+	if err := utils.VerifyPassword(request.Password, user.Password); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to disable two-factor authentication", err)
+	}
+
+	// Check second factor
+	ok, err := utils.VerifySecondFactor(user, request.Code)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+	}
+	if !ok {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to disable two-factor authentication", errors.New("invalid code"))
+	}
+
+	// Disable two-factor authentication
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"two_factor_enabled": false,
+			"two_factor_secret":  "",
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&entity.RecoveryCode{}).Error
+	})
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+	}
+
+	utils.Audit(ctx, "two_factor_disable", "success", user.ID, user.Email)
+
+	return utils.SendSuccessResponse(ctx, fiber.StatusOK, "Two-factor authentication has been disabled")
+}
+
+// LoginTwoFactor godoc
+// @Summary Complete login with a second factor
+// @Description Exchanges the challenge token returned by login, together with a TOTP or recovery code, for an access token and a refresh token. Each challenge token, TOTP code and recovery code works once.
+// @Tags Auth
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param challenge_token formData string true "Challenge Token"
+// @Param code formData string true "TOTP or Recovery Code"
+// @Router /login/2fa [post]
+func LoginTwoFactor(ctx *fiber.Ctx) error {
+	request := new(request.TwoFactorLoginRequest)
+
+	// Parse request body
+	if err := ctx.BodyParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to login", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to login", err)
+	}
+
+	// Check challenge token
+	userID, challengeID, err := utils.ParseTwoFactorChallenge(request.ChallengeToken)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidChallengeToken) {
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to login", err)
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to login", err)
+	}
+
+	// Find user
+	var user entity.User
+	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to login", utils.ErrInvalidChallengeToken)
+	}
+	if !user.TwoFactorEnabled {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to login", utils.ErrInvalidChallengeToken)
+	}
+
+	// Refuse while the account or IP address is backing off or locked
+	if err := utils.CheckLoginAllowed(user.Email, ctx.IP()); err != nil {
+		return sendLoginThrottled(ctx, user.Email, err)
+	}
+
+	// Check second factor
+	ok, err := utils.VerifySecondFactor(&user, request.Code)
+	if err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to login", err)
+	}
+	if !ok {
+		utils.Audit(ctx, "login", "wrong_second_factor", user.ID, user.Email)
+		recordLoginFailure(ctx, user.Email, &user)
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to login", errors.New("invalid code"))
+	}
+
+	// Each challenge token is exchanged once
+	if err := utils.ConsumeTwoFactorChallenge(challengeID); err != nil {
+		if errors.Is(err, utils.ErrInvalidChallengeToken) {
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to login", err)
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to login", err)
+	}
+
+	utils.Audit(ctx, "login", "success", user.ID, user.Email)
+
+	return sendLoginTokens(ctx, &user)
+}
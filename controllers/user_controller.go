@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"go-news-api/models/request"
+	"go-news-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// UpdateUserRole godoc
+// @Summary Change the role of a user
+// @Description Sets the role of a user to admin, editor, author or reader. Requires the users:manage permission. Admins cannot change their own role.
+// @Tags Users
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Param role formData string true "Role"
+// @Router /users/{id}/role [put]
+func UpdateUserRole(ctx *fiber.Ctx) error {
+	// Get User
+	admin := ctx.Locals("user").(*entity.User)
+	if admin == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to update role", errors.New("user not found"))
+	}
+
+	// Check if user exists
+	var user entity.User
+	if err := database.DB.First(&user, "id = ?", ctx.Params("id")).Error; err != nil {
+		// If user not found
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to update role", err)
+		}
+		// If error occurred
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update role", err)
+	}
+
+	// Prevent admins from locking themselves out
+	if user.ID == admin.ID {
+		return utils.SendErrorResponse(ctx, fiber.StatusForbidden, "Failed to update role", errors.New("you cannot change your own role"))
+	}
+
+	// Parse request body
+	request := new(request.UpdateUserRoleRequest)
+	if err := ctx.BodyParser(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to update role", err)
+	}
+
+	// Validate request
+	if err := utils.Validate.Struct(request); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusBadRequest, "Failed to update role", err)
+	}
+
+	// Update role
+	before := utils.AuditSnapshot(user)
+	user.Role = entity.UserRole(request.Role)
+	if err := database.DB.Model(&user).Update("role", user.Role).Error; err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update role", err)
+	}
+	utils.AuditChange(ctx, "user_role_update", "user", user.ID, before, user)
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully updated role", fiber.Map{
+		"user": user,
+	})
+}
+
+// VerifyUser godoc
+// @Summary Mark a user as verified
+// @Description Marks the email of a user as verified without an OTP code, for example after confirming it by other means. Requires the users:manage permission.
+// @Tags Users
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Router /users/{id}/verify [put]
+func VerifyUser(ctx *fiber.Ctx) error {
+	// Get User
+	admin := ctx.Locals("user").(*entity.User)
+	if admin == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Failed to verify user", errors.New("user not found"))
+	}
+
+	// Check if user exists
+	var user entity.User
+	if err := database.DB.First(&user, "id = ?", ctx.Params("id")).Error; err != nil {
+		// If user not found
+		if err == gorm.ErrRecordNotFound {
+			return utils.SendErrorResponse(ctx, fiber.StatusNotFound, "Failed to verify user", err)
+		}
+		// If error occurred
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to verify user", err)
+	}
+
+	// Mark as verified
+	if !user.IsVerified {
+		before := utils.AuditSnapshot(user)
+		if err := utils.MarkUserVerified(&user); err != nil {
+			return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to verify user", err)
+		}
+		utils.AuditChange(ctx, "user_verify", "user", user.ID, before, user)
+	}
+
+	return utils.SendSuccessResponseWithData(ctx, fiber.StatusOK, "Successfully verified user", fiber.Map{
+		"user": user,
+	})
+}
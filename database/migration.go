@@ -3,13 +3,124 @@ package database
 import (
 	"fmt"
 	"go-news-api/models/entity"
+	"strings"
+
+	"gorm.io/gorm"
 )
 
 func MigrateDatabase() {
-	err := DB.AutoMigrate(&entity.Category{}, &entity.User{}, &entity.OtpCode{}, &entity.Article{}, &entity.Comment{}, &entity.Tag{}, &entity.ArticleTag{})
+	// Articles from before the editorial workflow were all public
+	publishExisting := DB.Migrator().HasTable(&entity.Article{}) && !DB.Migrator().HasColumn(&entity.Article{}, "Status")
+
+	err := DB.AutoMigrate(&entity.Category{}, &entity.User{}, &entity.OtpCode{}, &entity.Article{}, &entity.Comment{}, &entity.Tag{}, &entity.ArticleTag{}, &entity.ArticleRevision{}, &entity.ArticleSlug{}, &entity.Session{}, &entity.RefreshToken{}, &entity.RecoveryCode{}, &entity.TwoFactorChallenge{}, &entity.PasswordResetToken{}, &entity.LoginAttempt{}, &entity.RateLimitBucket{}, &entity.UserIdentity{}, &entity.OAuthState{}, &entity.APIKey{}, &entity.SigningKey{}, &entity.AuditEvent{})
 	if err != nil {
 		panic("Failed to migrate database: " + err.Error())
 	}
 
+	if publishExisting {
+		if err := publishExistingArticles(); err != nil {
+			panic("Failed to migrate database: " + err.Error())
+		}
+	}
+
+	if err := ensureDeletedUser(); err != nil {
+		panic("Failed to migrate database: " + err.Error())
+	}
+
+	if err := keepCommentReplies(); err != nil {
+		panic("Failed to migrate database: " + err.Error())
+	}
+
+	if err := protectAuditEvents(); err != nil {
+		panic("Failed to migrate database: " + err.Error())
+	}
+
 	fmt.Println("Successfully migrated the database.")
 }
+
+// publishExistingArticles publishes the articles that got the draft status when the status column was added,
+// as of when they were created.
+func publishExistingArticles() error {
+	return DB.Model(&entity.Article{}).Where("published_at IS NULL").UpdateColumns(map[string]interface{}{
+		"status":       entity.Published,
+		"published_at": gorm.Expr("created_at"),
+	}).Error
+}
+
+// ensureDeletedUser creates the system user that anonymized content is shown under. A placeholder created on
+// demand by earlier versions has no password and no linked identities, and is flagged instead. An account that
+// was registered with the address is never trusted.
+func ensureDeletedUser() error {
+	var count int64
+	if err := DB.Model(&entity.User{}).Where("is_system = ?", true).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var existing entity.User
+	err := DB.Where("email = ?", entity.DeletedUserEmail).First(&existing).Error
+	if err == gorm.ErrRecordNotFound {
+		return DB.Create(&entity.User{
+			Name:     "Deleted user",
+			Email:    entity.DeletedUserEmail,
+			Role:     entity.ReaderRole,
+			IsSystem: true,
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	var identities int64
+	if err := DB.Model(&entity.UserIdentity{}).Where("user_id = ?", existing.ID).Count(&identities).Error; err != nil {
+		return err
+	}
+	if existing.Password != "" || identities > 0 {
+		return fmt.Errorf("%s belongs to a registered account, change its email before migrating", entity.DeletedUserEmail)
+	}
+	return DB.Model(&existing).Update("is_system", true).Error
+}
+
+// keepCommentReplies replaces the cascading foreign key earlier versions created between comments and their
+// replies, which AutoMigrate leaves as it is.
+func keepCommentReplies() error {
+	var count int64
+	if err := DB.Raw("SELECT COUNT(*) FROM information_schema.referential_constraints WHERE constraint_schema = DATABASE() AND table_name = 'comments' AND referenced_table_name = 'comments' AND delete_rule = 'CASCADE'").Scan(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+
+	if err := DB.Migrator().DropConstraint(&entity.Comment{}, "Parent"); err != nil {
+		return err
+	}
+	return DB.Migrator().CreateConstraint(&entity.Comment{}, "Parent")
+}
+
+// protectAuditEvents adds triggers that refuse to update or delete audit events, so the audit log stays
+// append-only for queries that do not go through the GORM hooks of entity.AuditEvent.
+func protectAuditEvents() error {
+	for _, statement := range []string{"UPDATE", "DELETE"} {
+		trigger := "audit_events_no_" + strings.ToLower(statement)
+
+		// Only create missing triggers, so the API can run without the TRIGGER privilege afterwards
+		var count int64
+		if err := DB.Raw("SELECT COUNT(*) FROM information_schema.triggers WHERE trigger_schema = DATABASE() AND trigger_name = ?", trigger).Scan(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		if err := DB.Exec(fmt.Sprintf(
+			"CREATE TRIGGER %s BEFORE %s ON audit_events FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = '%s'",
+			trigger, statement, entity.ErrAuditAppendOnly,
+		)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -74,8 +74,8 @@ func seedUsers() error {
 	}
 
 	users := []entity.User{
-		{Name: "Gojo Satoru", Email: "gojo@gmail.com", Password: string(hashedPassword), IsVerified: true},
-		{Name: "Ryomen Sukuna", Email: "sukuna@gmailcom", Password: string(hashedPassword), IsVerified: true},
+		{Name: "Gojo Satoru", Email: "gojo@gmail.com", Password: string(hashedPassword), Role: entity.AdminRole, IsVerified: true},
+		{Name: "Ryomen Sukuna", Email: "sukuna@gmailcom", Password: string(hashedPassword), Role: entity.EditorRole, IsVerified: true},
 	}
 
 	for _, user := range users {
@@ -98,6 +98,7 @@ func seedArticles() error {
 			Slug:       "future-of-ai-in-education",
 			Thumbnail:  "https://example.com/images/ai-education.jpg",
 			Content:    "Artificial Intelligence is revolutionizing the education sector...",
+			Status:     entity.Published,
 			CategoryID: 1,
 			AuthorID:   1,
 		},
@@ -106,6 +107,7 @@ func seedArticles() error {
 			Slug:       "top-10-movies-2024",
 			Thumbnail:  "https://example.com/images/movies-2024.jpg",
 			Content:    "2024 has been an exceptional year for cinema. Here are our top picks...",
+			Status:     entity.Published,
 			CategoryID: 2,
 			AuthorID:   2,
 		},
@@ -114,6 +116,7 @@ func seedArticles() error {
 			Slug:       "breakthrough-cancer-research",
 			Thumbnail:  "https://example.com/images/cancer-research.jpg",
 			Content:    "Scientists have made a groundbreaking discovery in cancer treatment...",
+			Status:     entity.Published,
 			CategoryID: 3,
 			AuthorID:   1,
 		},
@@ -122,6 +125,7 @@ func seedArticles() error {
 			Slug:       "rise-of-kpop-globally",
 			Thumbnail:  "https://example.com/images/kpop.jpg",
 			Content:    "K-Pop has taken the world by storm. We explore its global impact...",
+			Status:     entity.Published,
 			CategoryID: 4,
 			AuthorID:   2,
 		},
@@ -130,6 +134,7 @@ func seedArticles() error {
 			Slug:       "quantum-computing-new-era",
 			Thumbnail:  "https://example.com/images/quantum-computing.jpg",
 			Content:    "Quantum computing is set to revolutionize technology as we know it...",
+			Status:     entity.Published,
 			CategoryID: 5,
 			AuthorID:   1,
 		},
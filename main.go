@@ -4,6 +4,9 @@ import (
 	"go-news-api/cmd"
 	"go-news-api/database"
 	"go-news-api/routes"
+	"go-news-api/utils"
+	"os"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -29,19 +32,58 @@ func main() {
 	// Migrate database
 	database.MigrateDatabase()
 
-	// Initialize fiber app
-	app := fiber.New()
+	// Cobra for cli
+	cmd.Execute(serve)
+}
+
+func serve() {
+	// Set up article search index
+	if err := utils.SetupSearch(os.Getenv("SEARCH_DRIVER")); err != nil {
+		panic("Failed to set up search index: " + err.Error())
+	}
+
+	// Set up login attempt store
+	if err := utils.SetupLoginThrottle(os.Getenv("LOGIN_THROTTLE_STORE")); err != nil {
+		panic("Failed to set up login throttle: " + err.Error())
+	}
+
+	// Set up rate limit store
+	if err := utils.SetupRateLimit(os.Getenv("RATE_LIMIT_STORE")); err != nil {
+		panic("Failed to set up rate limit: " + err.Error())
+	}
+
+	// Set up JWT signing keys
+	if err := utils.EnsureSigningKey(); err != nil {
+		panic("Failed to set up signing keys: " + err.Error())
+	}
+
+	// Encrypt TOTP secrets stored before they were encrypted
+	if err := utils.EncryptStoredTwoFactorSecrets(); err != nil {
+		panic("Failed to encrypt two-factor secrets: " + err.Error())
+	}
+
+	// Start scheduled article publisher
+	utils.StartPublisher(utils.GetEnvDuration("PUBLISH_INTERVAL", time.Minute))
+
+	// Start purging accounts whose deletion grace period has passed
+	utils.StartAccountPurger(utils.GetEnvDuration("ACCOUNT_PURGE_INTERVAL", time.Hour))
+
+	// Initialize fiber app, taking the client IP from PROXY_HEADER only on requests from TRUSTED_PROXIES
+	app := fiber.New(fiber.Config{
+		ProxyHeader:             os.Getenv("PROXY_HEADER"),
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          utils.GetEnvList("TRUSTED_PROXIES"),
+		EnableIPValidation:      true,
+	})
 
 	// Add CORS middleware
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowMethods: "GET,POST,HEAD,PUT,DELETE,PATCH",
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowOrigins:  "*",
+		AllowMethods:  "GET,POST,HEAD,PUT,DELETE,PATCH",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, X-API-Key",
+		ExposeHeaders: "RateLimit-Policy, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After",
 	}))
 
-	// Cobra for cli
-	cmd.Execute()
-
 	// Swagger for api docs
 	app.Get("/swagger/*", swagger.HandlerDefault)
 
@@ -11,7 +11,15 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// AuthMiddleware authenticates the request with a Bearer JWT access token, or with a personal API key sent
+// in the X-API-Key header or as the Bearer token. API key requests have no session and are limited to the
+// key's scopes.
 func AuthMiddleware(ctx *fiber.Ctx) error {
+	// Check API key header
+	if apiKey := ctx.Get("X-API-Key"); apiKey != "" {
+		return authenticateAPIKey(ctx, apiKey)
+	}
+
 	// Check token from Authorization header
 	authHeader := ctx.Get("Authorization")
 	if authHeader == "" {
@@ -29,6 +37,11 @@ func AuthMiddleware(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized", errors.New("token is empty"))
 	}
 
+	// API keys can also be sent as the Bearer token
+	if utils.IsAPIKey(tokenString) {
+		return authenticateAPIKey(ctx, tokenString)
+	}
+
 	// Parse and validate token
 	token, err := utils.ParseToken(tokenString)
 	if err != nil {
@@ -47,14 +60,136 @@ func AuthMiddleware(ctx *fiber.Ctx) error {
 		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized", errors.New("invalid or expired token"))
 	}
 
+	// Get session id from claims
+	sessionID, ok := claims["sid"].(string)
+	if !ok {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized", errors.New("invalid or expired token"))
+	}
+
+	// Check that the session has not been revoked
+	var session entity.Session
+	if err := database.DB.First(&session, "id = ? AND user_id = ?", sessionID, userID).Error; err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized", errors.New("session not found"))
+	}
+	if session.RevokedAt != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized", errors.New("session has been revoked"))
+	}
+
+	// Update last seen time of the session
+	if err := utils.TouchSession(&session, ctx.IP()); err != nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update session", err)
+	}
+
 	// Find user
 	var user entity.User
 	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
 		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized", err)
 	}
+	if user.IsSystem {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized", utils.ErrSystemAccount)
+	}
 
-	// Attach user to context
+	// Attach user and session to context
 	ctx.Locals("user", &user)
+	ctx.Locals("session", &session)
+
+	return ctx.Next()
+}
+
+func authenticateAPIKey(ctx *fiber.Ctx, key string) error {
+	apiKey, user, err := utils.AuthenticateAPIKey(key)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidAPIKey) {
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized", err)
+		}
+		return utils.SendErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to check API key", err)
+	}
+	if user.IsSystem {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized", utils.ErrSystemAccount)
+	}
+
+	// Attach user and API key to context
+	ctx.Locals("user", user)
+	ctx.Locals("api_key", apiKey)
+
+	return ctx.Next()
+}
+
+// RequireSession rejects API key requests, for account security endpoints that need a real login.
+// It must run after AuthMiddleware.
+func RequireSession(ctx *fiber.Ctx) error {
+	if session, ok := ctx.Locals("session").(*entity.Session); !ok || session == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusForbidden, "Forbidden", errors.New("this endpoint cannot be used with an API key"))
+	}
+	return ctx.Next()
+}
+
+// RequireScope only lets API key requests through when the key has all of the given scopes.
+// Session logins are not limited by scopes. It must run after AuthMiddleware.
+func RequireScope(scopes ...utils.Permission) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		user, ok := ctx.Locals("user").(*entity.User)
+		if !ok || user == nil {
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized", errors.New("user not found"))
+		}
+
+		for _, scope := range scopes {
+			if !utils.HasScope(user, scope) {
+				return utils.SendErrorResponse(ctx, fiber.StatusForbidden, "Forbidden", errors.New("API key is missing scope "+string(scope)))
+			}
+		}
+
+		return ctx.Next()
+	}
+}
+
+// RequireRole only lets users with one of the given roles through. It must run after AuthMiddleware.
+func RequireRole(roles ...entity.UserRole) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		user, ok := ctx.Locals("user").(*entity.User)
+		if !ok || user == nil {
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized", errors.New("user not found"))
+		}
+
+		for _, role := range roles {
+			if user.Role == role {
+				return ctx.Next()
+			}
+		}
+
+		return utils.SendErrorResponse(ctx, fiber.StatusForbidden, "Forbidden", errors.New("your role is not allowed to do this"))
+	}
+}
+
+// RequireVerified only lets users with a verified email through, apart from new accounts still within
+// the verification grace period. It must run after AuthMiddleware.
+func RequireVerified(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*entity.User)
+	if !ok || user == nil {
+		return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized", errors.New("user not found"))
+	}
+
+	if utils.RequiresVerification(user) {
+		return utils.SendErrorResponseWithCode(ctx, fiber.StatusForbidden, utils.EmailNotVerifiedCode, "Email not verified", utils.ErrVerificationRequired)
+	}
 
 	return ctx.Next()
 }
+
+// RequirePermission only lets users whose role has all of the given permissions through. It must run after AuthMiddleware.
+func RequirePermission(permissions ...utils.Permission) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		user, ok := ctx.Locals("user").(*entity.User)
+		if !ok || user == nil {
+			return utils.SendErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized", errors.New("user not found"))
+		}
+
+		for _, permission := range permissions {
+			if !utils.HasPermission(user, permission) {
+				return utils.SendErrorResponse(ctx, fiber.StatusForbidden, "Forbidden", errors.New("missing permission "+string(permission)))
+			}
+		}
+
+		return ctx.Next()
+	}
+}
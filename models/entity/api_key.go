@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// APIKey is a personal key a user creates for scripts and integrations. Only a hash of the key is stored.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	User       User       `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(16);not null" json:"prefix"`
+	KeyHash    string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	Scopes     []string   `gorm:"type:text;serializer:json" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
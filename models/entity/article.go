@@ -8,20 +8,26 @@ const (
 	Published ArticleStatus = "published"
 	Draft     ArticleStatus = "draft"
 	Review    ArticleStatus = "review"
+	Approved  ArticleStatus = "approved"
 )
 
 type Article struct {
-	ID         uint          `gorm:"primaryKey" json:"id"`
-	Title      string        `gorm:"type:varchar(100);not null" json:"title"`
-	Slug       string        `gorm:"type:varchar(100);not null;unique" json:"slug"`
-	Thumbnail  string        `gorm:"type:varchar(100);not null" json:"thumbnail"`
-	Content    string        `gorm:"type:text;not null" json:"content"`
-	CategoryID uint          `gorm:"not null" json:"category_id"`
-	Category   Category      `gorm:"foreignKey:CategoryID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"category"`
-	Tags       []Tag         `gorm:"many2many:article_tags;" json:"tags"`
-	Author     User          `gorm:"foreignKey:AuthorID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"author"`
-	AuthorID   uint          `gorm:"not null" json:"author_id"`
-	Comments   []Comment     `gorm:"foreignKey:ArticleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"comments"`
-	CreatedAt  time.Time     `json:"created_at"`
-	UpdatedAt  time.Time     `json:"updated_at"`
+	ID              uint          `gorm:"primaryKey" json:"id"`
+	Title           string        `gorm:"type:varchar(100);not null;index:idx_articles_search,class:FULLTEXT" json:"title"`
+	Slug            string        `gorm:"type:varchar(100);not null;unique" json:"slug"`
+	Thumbnail       string        `gorm:"type:varchar(100);not null" json:"thumbnail"`
+	Content         string        `gorm:"type:text;not null;index:idx_articles_search,class:FULLTEXT" json:"content"`
+	Status          ArticleStatus `gorm:"type:enum('draft', 'review', 'approved', 'published');not null;default:'draft';index" json:"status"`
+	RejectionReason string        `gorm:"type:varchar(500)" json:"rejection_reason,omitempty"`
+	PublishAt       *time.Time    `gorm:"index" json:"publish_at"`
+	PublishedAt     *time.Time    `json:"published_at"`
+	CategoryID      uint          `gorm:"not null" json:"category_id"`
+	Category        Category      `gorm:"foreignKey:CategoryID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"category"`
+	Tags            []Tag         `gorm:"many2many:article_tags;" json:"tags"`
+	Author          User          `gorm:"foreignKey:AuthorID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"author"`
+	AuthorID        uint          `gorm:"not null" json:"author_id"`
+	Comments        []Comment     `gorm:"foreignKey:ArticleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"comments,omitempty"`
+	CommentCount    int64         `gorm:"->;-:migration" json:"comment_count"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
 }
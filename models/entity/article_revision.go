@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+type ArticleRevision struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ArticleID  uint      `gorm:"not null;uniqueIndex:idx_article_revision_version" json:"article_id"`
+	Article    Article   `gorm:"foreignKey:ArticleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Version    uint      `gorm:"not null;uniqueIndex:idx_article_revision_version" json:"version"`
+	Title      string    `gorm:"type:varchar(100);not null" json:"title"`
+	Slug       string    `gorm:"type:varchar(100);not null" json:"slug"`
+	Thumbnail  string    `gorm:"type:varchar(100);not null" json:"thumbnail"`
+	Content    string    `gorm:"type:text;not null" json:"content"`
+	CategoryID uint      `gorm:"not null" json:"category_id"`
+	Tags       []string  `gorm:"type:text;serializer:json" json:"tags"`
+	EditorID   uint      `gorm:"not null" json:"editor_id"`
+	Editor     User      `gorm:"foreignKey:EditorID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"editor"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// ArticleSlug keeps a slug an article used before it was renamed, so old links can be redirected.
+type ArticleSlug struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Slug      string    `gorm:"type:varchar(100);not null;unique" json:"slug"`
+	ArticleID uint      `gorm:"not null;index" json:"article_id"`
+	Article   Article   `gorm:"foreignKey:ArticleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrAuditAppendOnly = errors.New("audit events cannot be changed or deleted")
+
+// AuditEvent is one entry of the append-only audit log. The actor is kept by ID and email without a
+// foreign key, so events outlive the accounts and resources they mention. Besides the hooks below, the migration
+// adds database triggers that refuse to update or delete events.
+type AuditEvent struct {
+	ID         uint            `gorm:"primaryKey" json:"id"`
+	ActorID    *uint           `gorm:"index" json:"actor_id"`
+	ActorEmail string          `gorm:"type:varchar(100)" json:"actor_email,omitempty"`
+	Action     string          `gorm:"type:varchar(64);not null;index" json:"action"`
+	Outcome    string          `gorm:"type:varchar(32);not null" json:"outcome"`
+	TargetType string          `gorm:"type:varchar(32);index:idx_audit_events_target,priority:1" json:"target_type,omitempty"`
+	TargetID   string          `gorm:"type:varchar(191);index:idx_audit_events_target,priority:2" json:"target_id,omitempty"`
+	Before     json.RawMessage `gorm:"type:json" json:"before,omitempty"`
+	After      json.RawMessage `gorm:"type:json" json:"after,omitempty"`
+	IPAddress  string          `gorm:"type:varchar(45)" json:"ip_address"`
+	UserAgent  string          `gorm:"type:varchar(255)" json:"user_agent"`
+	CreatedAt  time.Time       `gorm:"index" json:"created_at"`
+}
+
+func (AuditEvent) BeforeUpdate(*gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+func (AuditEvent) BeforeDelete(*gorm.DB) error {
+	return ErrAuditAppendOnly
+}
@@ -13,4 +13,15 @@ type Comment struct {
 	Article   Article   `gorm:"foreignKey:ArticleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// ParentID is the comment this one replies to, top-level comments have none and a depth of 0.
+	// Deleting a comment never deletes its replies, they are kept under a placeholder by utils.DeleteComment.
+	ParentID *uint    `gorm:"index" json:"parent_id"`
+	Parent   *Comment `gorm:"foreignKey:ParentID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"-"`
+	Depth    int      `gorm:"not null;default:0" json:"depth"`
+
+	// IsDeleted marks a placeholder left behind by a deleted comment that still has replies.
+	IsDeleted  bool      `gorm:"default:false" json:"is_deleted"`
+	ReplyCount int64     `gorm:"->;-:migration" json:"reply_count"`
+	Replies    []Comment `gorm:"-" json:"replies,omitempty"`
 }
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// LoginAttempt tracks failed logins for one account or IP address, keyed like "account:<email>" or "ip:<address>".
+type LoginAttempt struct {
+	Key             string     `gorm:"column:throttle_key;primaryKey;type:varchar(191)" json:"key"`
+	Failures        int        `gorm:"not null;default:0" json:"failures"`
+	LastFailureAt   time.Time  `json:"last_failure_at"`
+	BlockedUntil    *time.Time `json:"blocked_until"`
+	LockedUntil     *time.Time `gorm:"index" json:"locked_until"`
+	UnlockTokenHash string     `gorm:"type:char(64)" json:"-"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
@@ -7,14 +7,18 @@ type OtpType string
 const (
 	EmailVerification OtpType = "email_verification"
 	PasswordReset     OtpType = "password_reset"
+	EmailChange       OtpType = "email_change"
+	AccountDeletion   OtpType = "account_deletion"
 )
 
 type OtpCode struct {
-	ID         uint      `gorm:"primaryKey" json:"id"`
-	Otp        string    `gorm:"type:varchar(4);not null" json:"otp"`
-	Type       OtpType   `gorm:"type:enum('email_verification', 'password_reset');not null" json:"type"`
-	ExpiredAt  time.Time `json:"expired_at"`
-	IsVerified bool      `gorm:"default:false" json:"is_verified"`
-	UserID     uint      `gorm:"not null" json:"user_id"`
-	User       User      `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"user"`
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Otp         string     `gorm:"type:char(64);not null" json:"-"`
+	Type        OtpType    `gorm:"type:enum('email_verification', 'password_reset', 'email_change', 'account_deletion');not null;uniqueIndex:idx_otp_codes_user_type,priority:2" json:"type"`
+	ExpiredAt   time.Time  `json:"expired_at"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	LockedUntil *time.Time `json:"locked_until"`
+	SentAt      time.Time  `json:"sent_at"`
+	UserID      uint       `gorm:"not null;uniqueIndex:idx_otp_codes_user_type,priority:1" json:"user_id"`
+	User        User       `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"user"`
 }
@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// RateLimitBucket is the token bucket of one rate limit policy and client, keyed like "<policy>:<client>".
+type RateLimitBucket struct {
+	Key        string    `gorm:"column:bucket_key;primaryKey;type:varchar(191)" json:"key"`
+	Tokens     float64   `gorm:"not null" json:"tokens"`
+	RefilledAt time.Time `gorm:"type:datetime(6);not null" json:"refilled_at"`
+	// FullAt is when the bucket has refilled completely, after which it is deleted.
+	FullAt time.Time `gorm:"type:datetime(6);index" json:"full_at"`
+}
@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// RecoveryCode is a one-time code that can be used instead of a TOTP code when signing in.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	User      User       `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	CodeHash  string     `gorm:"type:char(64);not null;unique" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// Session is one login. Every refresh token issued for the login belongs to it,
+// so revoking the session revokes the whole token family.
+type Session struct {
+	ID         string     `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	User       User       `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	UserAgent  string     `gorm:"type:varchar(255)" json:"user_agent"`
+	IPAddress  string     `gorm:"type:varchar(45)" json:"ip_address"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	TokenHash string     `gorm:"type:char(64);not null;unique" json:"-"`
+	SessionID string     `gorm:"type:char(36);not null;index" json:"session_id"`
+	Session   Session    `gorm:"foreignKey:SessionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
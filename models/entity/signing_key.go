@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// SigningKey is a key pair that signs JWTs. The newest activated key signs new tokens, while
+// every key that has not retired yet verifies tokens and is published in the JWKS. The private key is
+// stored as PEM encrypted with JWT_KEY_ENCRYPTION_KEY.
+type SigningKey struct {
+	Kid         string     `gorm:"primaryKey;type:varchar(64)" json:"kid"`
+	Algorithm   string     `gorm:"type:varchar(10);not null" json:"algorithm"`
+	PrivateKey  string     `gorm:"type:text;not null" json:"-"`
+	ActivatesAt time.Time  `gorm:"not null" json:"activates_at"`
+	RetiresAt   *time.Time `gorm:"index" json:"retires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// TwoFactorChallenge is a challenge token issued after a correct password. The row is deleted when the token is
+// exchanged for a session, so every challenge token works once.
+type TwoFactorChallenge struct {
+	ID        string    `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	User      User      `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -2,12 +2,89 @@ package entity
 
 import "time"
 
+type UserRole string
+
+const (
+	AdminRole  UserRole = "admin"
+	EditorRole UserRole = "editor"
+	AuthorRole UserRole = "author"
+	ReaderRole UserRole = "reader"
+)
+
+// DeletedUserEmail is the address of the system user that anonymized content is shown under.
+// It is in the reserved .invalid domain, which cannot be registered.
+const DeletedUserEmail = "deleted-user@users.invalid"
+
+// DeletionMode is what happens to the articles and comments of a deleted account.
+type DeletionMode string
+
+const (
+	// AnonymizeContent keeps the content and reassigns it to the deleted user placeholder.
+	AnonymizeContent DeletionMode = "anonymize"
+	// DeleteContent deletes the content together with the account.
+	DeleteContent DeletionMode = "delete"
+)
+
 type User struct {
-	ID         uint      `gorm:"primaryKey" json:"id"`
-	Name       string    `gorm:"type:varchar(100);not null" json:"name"`
-	Email      string    `gorm:"type:varchar(100);not null;unique" json:"email"`
-	Password   string    `gorm:"type:varchar(100);column:password;not null" json:"-"`
-	IsVerified bool      `gorm:"default:false" json:"is_verified"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID         uint     `gorm:"primaryKey" json:"id"`
+	Name       string   `gorm:"type:varchar(100);not null" json:"name"`
+	Email      string   `gorm:"type:varchar(100);not null;unique" json:"email"`
+	Password   string   `gorm:"type:varchar(100);column:password;not null" json:"-"`
+	Role       UserRole `gorm:"type:enum('admin', 'editor', 'author', 'reader');not null;default:'author'" json:"role"`
+	IsVerified bool     `gorm:"default:false" json:"is_verified"`
+	// IsSystem marks accounts the API creates for itself, such as the deleted user placeholder. Nobody can sign in as them.
+	IsSystem  bool      `gorm:"not null;default:false;index" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// PendingEmail is the address the user is changing to until it is confirmed with an OTP.
+	// Users are embedded in public responses, so it is only shown to the user on their profile.
+	PendingEmail string `gorm:"type:varchar(100)" json:"-"`
+
+	Avatar      string      `gorm:"type:varchar(255)" json:"avatar"`
+	Bio         string      `gorm:"type:varchar(500)" json:"bio"`
+	SocialLinks SocialLinks `gorm:"type:text;serializer:json" json:"social_links"`
+
+	// DeletionScheduledAt is when the account will be deleted, unless the user cancels before then.
+	// Like PendingEmail, it is only shown to the user on their profile.
+	DeletionScheduledAt *time.Time   `gorm:"index" json:"-"`
+	DeletionMode        DeletionMode `gorm:"type:varchar(10)" json:"-"`
+
+	// TwoFactorSecret is stored encrypted with the key encryption key. Like PendingEmail, TwoFactorEnabled
+	// is only shown to the user on their profile.
+	TwoFactorSecret  string `gorm:"type:varchar(255)" json:"-"`
+	TwoFactorEnabled bool   `gorm:"default:false" json:"-"`
+	// TwoFactorLastStep is the TOTP time step of the last accepted code, codes from it or earlier steps are rejected.
+	TwoFactorLastStep int64 `gorm:"not null;default:0" json:"-"`
+
+	// Scopes limits the permissions of a request made with an API key. It is nil for session logins.
+	Scopes []string `gorm:"-" json:"-"`
+}
+
+// Profile is the user's own view of their account, with the fields other users cannot see.
+type Profile struct {
+	*User
+	PendingEmail        string       `json:"pending_email,omitempty"`
+	DeletionScheduledAt *time.Time   `json:"deletion_scheduled_at,omitempty"`
+	DeletionMode        DeletionMode `json:"deletion_mode,omitempty"`
+	TwoFactorEnabled    bool         `json:"two_factor_enabled"`
+}
+
+// Profile returns the user's own view of their account.
+func (user *User) Profile() Profile {
+	return Profile{
+		User:                user,
+		PendingEmail:        user.PendingEmail,
+		DeletionScheduledAt: user.DeletionScheduledAt,
+		DeletionMode:        user.DeletionMode,
+		TwoFactorEnabled:    user.TwoFactorEnabled,
+	}
+}
+
+// SocialLinks are the profiles a user links to from their public author page.
+type SocialLinks struct {
+	Website  string `json:"website,omitempty"`
+	Twitter  string `json:"twitter,omitempty"`
+	Github   string `json:"github,omitempty"`
+	Linkedin string `json:"linkedin,omitempty"`
 }
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// UserIdentity links a user to an account at an external OpenID Connect provider.
+type UserIdentity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_user_identities_user_provider,priority:1" json:"user_id"`
+	User      User      `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Provider  string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_user_identities_provider_subject,priority:1;uniqueIndex:idx_user_identities_user_provider,priority:2" json:"provider"`
+	Subject   string    `gorm:"type:varchar(191);not null;uniqueIndex:idx_user_identities_provider_subject,priority:2" json:"-"`
+	Email     string    `gorm:"type:varchar(100)" json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OAuthState remembers an authorization request started with a provider until its callback arrives.
+type OAuthState struct {
+	State        string    `gorm:"type:varchar(64);primaryKey" json:"-"`
+	Provider     string    `gorm:"type:varchar(50);not null" json:"provider"`
+	CodeVerifier string    `gorm:"type:varchar(128);not null" json:"-"`
+	Nonce        string    `gorm:"type:varchar(64);not null" json:"-"`
+	BindingHash  string    `gorm:"type:varchar(64);not null" json:"-"`
+	LinkUserID   *uint     `json:"link_user_id"`
+	ExpiresAt    time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
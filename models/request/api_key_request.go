@@ -0,0 +1,7 @@
+package request
+
+type CreateAPIKeyRequest struct {
+	Name      string   `json:"name" form:"name" validate:"required,min=3,max=100"`
+	Scopes    []string `json:"scopes" form:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresAt string   `json:"expires_at" form:"expires_at" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
@@ -1,19 +1,35 @@
 package request
 
 type CreateArticleRequest struct {
-	Title      string `json:"title" validate:"required,min=3,max=100"`
-	Slug       string `json:"slug" validate:"required,min=3,max=100"`
-	Thumbnail  string `json:"thumbnail"`
-	Content    string `json:"content" validate:"required"`
-	CategoryID uint   `json:"category_id" form:"category_id" validate:"required,category_exists"`
-	Tags       []string  `json:"tags" validate:"required"`
+	Title      string   `json:"title" validate:"required,min=3,max=100"`
+	Slug       string   `json:"slug" form:"slug" validate:"omitempty,min=3,max=100"`
+	Thumbnail  string   `json:"thumbnail"`
+	Content    string   `json:"content" validate:"required"`
+	CategoryID uint     `json:"category_id" form:"category_id" validate:"required,category_exists"`
+	Tags       []string `json:"tags" validate:"required"`
 }
 
 type UpdateArticleRequest struct {
-    Title      *string `json:"title" form:"title"`
-    Slug    *string `json:"slug" form:"slug"`
-	Thumbnail *string `json:"thumbnail" form:"thumbnail"`
-    Content    *string `json:"content" form:"content"`
-    CategoryID *uint    `json:"category_id" form:"category_id"`
-    Tags       []string `json:"tags" form:"tags"`
+	Title      *string  `json:"title" form:"title"`
+	Slug       *string  `json:"slug" form:"slug"`
+	Thumbnail  *string  `json:"thumbnail" form:"thumbnail"`
+	Content    *string  `json:"content" form:"content"`
+	CategoryID *uint    `json:"category_id" form:"category_id"`
+	Tags       []string `json:"tags" form:"tags"`
+}
+
+type RejectArticleRequest struct {
+	Reason string `json:"reason" form:"reason" validate:"required,max=500"`
+}
+
+type ScheduleArticleRequest struct {
+	PublishAt string `json:"publish_at" form:"publish_at" validate:"required,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+type SearchArticlesRequest struct {
+	Q          string `query:"q" validate:"required,max=200"`
+	CategoryID uint   `query:"category_id"`
+	Tag        string `query:"tag"`
+	Page       int    `query:"page" validate:"omitempty,min=1"`
+	PerPage    int    `query:"per_page" validate:"omitempty,min=1,max=100"`
 }
@@ -0,0 +1,12 @@
+package request
+
+type AuditQueryRequest struct {
+	PageRequest
+	ActorID    uint   `query:"actor_id"`
+	Action     string `query:"action" validate:"omitempty,max=64"`
+	Outcome    string `query:"outcome" validate:"omitempty,max=32"`
+	TargetType string `query:"target_type" validate:"omitempty,max=32"`
+	TargetID   string `query:"target_id" validate:"omitempty,max=191"`
+	From       string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To         string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+}
@@ -12,6 +12,11 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type UnlockAccountRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Token string `json:"token" validate:"required"`
+}
+
 type SendVerificationEmailRequest struct {
 	Email string `json:"email" validate:"required,email"`
 }
@@ -32,6 +37,25 @@ type VerifyOtpResetRequest struct {
 
 type ResetPasswordRequest struct {
 	Email                   string `json:"email" validate:"required,email"`
+	ResetToken              string `json:"reset_token" form:"reset_token" validate:"required"`
 	NewPassword             string `json:"new_password" form:"new_password" validate:"required,min=8"`
 	NewPasswordConfirmation string `json:"new_password_confirmation" form:"new_password_confirmation" validate:"required,min=8,eqfield=NewPassword"`
 }
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" form:"refresh_token" validate:"required"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" form:"code" validate:"required"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" form:"challenge_token" validate:"required"`
+	Code           string `json:"code" form:"code" validate:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" form:"password" validate:"required"`
+	Code     string `json:"code" form:"code" validate:"required"`
+}
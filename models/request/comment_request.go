@@ -2,4 +2,12 @@ package request
 
 type CommentRequest struct {
 	Content string `json:"content"`
-}
\ No newline at end of file
+	// ParentID is the comment being replied to. It is only read when creating a comment.
+	ParentID *uint `json:"parent_id" form:"parent_id"`
+}
+
+type CommentTreeRequest struct {
+	PageRequest
+	Depth          *int `query:"depth" validate:"omitempty,min=0"`
+	RepliesPerPage int  `query:"replies_per_page" validate:"omitempty,min=1,max=100"`
+}
@@ -0,0 +1,19 @@
+package request
+
+type PageRequest struct {
+	Page    int    `query:"page" validate:"omitempty,min=1"`
+	PerPage int    `query:"per_page" validate:"omitempty,min=1,max=100"`
+	Cursor  string `query:"cursor"`
+	Sort    string `query:"sort"`
+	Order   string `query:"order" validate:"omitempty,oneof=asc desc"`
+}
+
+type ArticleQueryRequest struct {
+	PageRequest
+	CategoryID uint   `query:"category_id"`
+	Tag        string `query:"tag"`
+	AuthorID   uint   `query:"author_id"`
+	Status     string `query:"status" validate:"omitempty,oneof=draft review approved published"`
+	From       string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To         string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+}
@@ -0,0 +1,39 @@
+package request
+
+type UpdateProfileRequest struct {
+	Name        *string             `json:"name" form:"name" validate:"omitempty,min=3,max=100"`
+	Bio         *string             `json:"bio" form:"bio" validate:"omitempty,max=500"`
+	SocialLinks *SocialLinksRequest `json:"social_links" form:"social_links"`
+}
+
+type SocialLinksRequest struct {
+	Website  string `json:"website" validate:"omitempty,http_url,max=255"`
+	Twitter  string `json:"twitter" validate:"omitempty,http_url,max=255"`
+	Github   string `json:"github" validate:"omitempty,http_url,max=255"`
+	Linkedin string `json:"linkedin" validate:"omitempty,http_url,max=255"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword         string `json:"current_password" form:"current_password" validate:"required"`
+	NewPassword             string `json:"new_password" form:"new_password" validate:"required,min=8"`
+	NewPasswordConfirmation string `json:"new_password_confirmation" form:"new_password_confirmation" validate:"required,min=8,eqfield=NewPassword"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" form:"new_email" validate:"required,email,max=100"`
+	Password string `json:"password" form:"password" validate:"required"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Otp string `json:"otp" form:"otp" validate:"required"`
+}
+
+type ExportAccountRequest struct {
+	Format string `query:"format" validate:"omitempty,oneof=zip json"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" form:"password"`
+	Otp      string `json:"otp" form:"otp"`
+	Content  string `json:"content" form:"content" validate:"required,oneof=anonymize delete"`
+}
@@ -0,0 +1,9 @@
+package request
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" form:"role" validate:"required,oneof=admin editor author reader"`
+}
+
+type ClearLockoutRequest struct {
+	Key string `json:"key" query:"key" validate:"required"`
+}
@@ -3,6 +3,9 @@ package routes
 import (
 	"go-news-api/controllers"
 	"go-news-api/middleware"
+	"go-news-api/models/entity"
+	"go-news-api/utils"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -14,46 +17,126 @@ func RouteInit(route *fiber.App) {
 		})
 	})
 
+	// Public keys that verify our tokens
+	route.Get("/.well-known/jwks.json", controllers.GetJWKS)
+
 	// Static asset
 	route.Static("/public", "./public")
 
 	// Prefix /api
 	api := route.Group("/api")
 
+	// Rate limit policies
+	authLimit := middleware.RateLimit("auth", 20, time.Minute, middleware.RateLimitByIP)
+	emailLimit := middleware.RateLimit("email", 5, 15*time.Minute, middleware.RateLimitByIP)
+	articleLimit := middleware.RateLimit("articles", 60, time.Hour, middleware.RateLimitByAPIKey)
+	commentLimit := middleware.RateLimit("comments", 10, time.Minute, middleware.RateLimitByUser)
+
+	// Author routes
+	api.Get("/authors/:id", controllers.GetAuthor)
+
 	// Category routes
 	api.Get("/categories", controllers.GetAllCategories)
 	api.Get("/categories/:id", controllers.GetCategoryById)
-	api.Post("/categories", controllers.CreateCategory)
-	api.Put("/categories/:id", controllers.UpdateCategory)
-	api.Delete("/categories/:id", controllers.DeleteCategory)
+	api.Post("/categories", middleware.AuthMiddleware, middleware.RequirePermission(utils.ManageCategories), controllers.CreateCategory)
+	api.Put("/categories/:id", middleware.AuthMiddleware, middleware.RequirePermission(utils.ManageCategories), controllers.UpdateCategory)
+	api.Delete("/categories/:id", middleware.AuthMiddleware, middleware.RequirePermission(utils.ManageCategories), controllers.DeleteCategory)
 
 	// Auth routes
-	api.Post("/register", controllers.Register)
-	api.Post("/login", controllers.Login)
-	api.Post("/email-verification/request", controllers.SendVerificationEmail)
-	api.Post("/email-verification/verify", controllers.VerifyEmail)
-	api.Get("/profile", middleware.AuthMiddleware, controllers.GetProfile)
-	api.Post("/reset-password/request", controllers.SendResetPasswordEmail)
-	api.Post("/reset-password/verify", controllers.VerifyOtpReset)
-	api.Post("/reset-password", controllers.ResetPassword)
+	api.Post("/register", emailLimit, controllers.Register)
+	api.Post("/login", authLimit, controllers.Login)
+	api.Post("/login/2fa", authLimit, controllers.LoginTwoFactor)
+	api.Post("/login/unlock", authLimit, controllers.UnlockAccount)
+	api.Post("/token/refresh", authLimit, controllers.RefreshToken)
+	api.Post("/logout", middleware.AuthMiddleware, middleware.RequireSession, controllers.Logout)
+	api.Post("/logout-all", middleware.AuthMiddleware, middleware.RequireSession, controllers.LogoutAll)
+	api.Post("/email-verification/request", emailLimit, controllers.SendVerificationEmail)
+	api.Post("/email-verification/verify", authLimit, controllers.VerifyEmail)
+	api.Get("/profile", middleware.AuthMiddleware, middleware.RequireScope(utils.ReadProfile), controllers.GetProfile)
+	api.Patch("/profile", middleware.AuthMiddleware, middleware.RequireScope(utils.WriteProfile), controllers.UpdateProfile)
+	api.Post("/profile/avatar", middleware.AuthMiddleware, middleware.RequireScope(utils.WriteProfile), controllers.UploadAvatar)
+	api.Delete("/profile/avatar", middleware.AuthMiddleware, middleware.RequireScope(utils.WriteProfile), controllers.DeleteAvatar)
+	api.Post("/profile/password", middleware.AuthMiddleware, middleware.RequireSession, authLimit, controllers.ChangePassword)
+	api.Post("/profile/email", middleware.AuthMiddleware, middleware.RequireSession, emailLimit, controllers.ChangeEmail)
+	api.Post("/profile/email/verify", middleware.AuthMiddleware, middleware.RequireSession, authLimit, controllers.ConfirmEmailChange)
+	api.Get("/profile/export", middleware.AuthMiddleware, middleware.RequireSession, emailLimit, controllers.ExportAccount)
+	api.Delete("/profile", middleware.AuthMiddleware, middleware.RequireSession, authLimit, controllers.DeleteAccount)
+	api.Post("/profile/restore", middleware.AuthMiddleware, middleware.RequireSession, controllers.RestoreAccount)
+	api.Post("/reset-password/request", emailLimit, controllers.SendResetPasswordEmail)
+	api.Post("/reset-password/verify", authLimit, controllers.VerifyOtpReset)
+	api.Post("/reset-password", authLimit, controllers.ResetPassword)
+
+	// Identity provider routes
+	api.Get("/auth/providers", controllers.GetOIDCProviders)
+	api.Get("/auth/:provider/login", authLimit, controllers.OIDCLogin)
+	api.Get("/auth/:provider/callback", authLimit, controllers.OIDCCallback)
+	api.Get("/profile/identities", middleware.AuthMiddleware, middleware.RequireSession, controllers.GetIdentities)
+	api.Post("/profile/identities/:provider", middleware.AuthMiddleware, middleware.RequireSession, controllers.LinkIdentity)
+	api.Delete("/profile/identities/:provider", middleware.AuthMiddleware, middleware.RequireSession, controllers.UnlinkIdentity)
+
+	// Two-factor authentication routes
+	twoFactor := api.Group("/2fa", middleware.AuthMiddleware, middleware.RequireSession, middleware.RequireRole(entity.AdminRole, entity.EditorRole, entity.AuthorRole))
+	twoFactor.Post("/enroll", controllers.EnrollTwoFactor)
+	twoFactor.Post("/confirm", controllers.ConfirmTwoFactor)
+	twoFactor.Post("/disable", controllers.DisableTwoFactor)
+
+	// API key routes
+	api.Get("/api-keys", middleware.AuthMiddleware, middleware.RequireSession, controllers.GetAPIKeys)
+	api.Post("/api-keys", middleware.AuthMiddleware, middleware.RequireSession, controllers.CreateAPIKey)
+	api.Delete("/api-keys/:id", middleware.AuthMiddleware, middleware.RequireSession, controllers.DeleteAPIKey)
+
+	// Session routes
+	api.Get("/sessions", middleware.AuthMiddleware, middleware.RequireSession, controllers.GetSessions)
+	api.Delete("/sessions/:id", middleware.AuthMiddleware, middleware.RequireSession, controllers.DeleteSession)
 
 	// Article routes
 	api.Get("/articles", controllers.GetAllArticles)
-	api.Get("/articles/me", middleware.AuthMiddleware, controllers.GetMyArticles)
+	api.Get("/articles/me", middleware.AuthMiddleware, middleware.RequireScope(utils.ReadArticles), controllers.GetMyArticles)
+	api.Get("/articles/search", controllers.SearchArticles)
 	api.Get("/articles/:slug", controllers.GetArticleBySlug)
-	api.Post("/articles", middleware.AuthMiddleware, controllers.CreateArticle)
-	api.Put("/articles/:slug", middleware.AuthMiddleware, controllers.UpdateArticle)
-	api.Delete("/articles/:slug", middleware.AuthMiddleware, controllers.DeleteArticle)
+	api.Post("/articles", middleware.AuthMiddleware, articleLimit, middleware.RequireVerified, middleware.RequirePermission(utils.WriteArticles), controllers.CreateArticle)
+	api.Put("/articles/:slug", middleware.AuthMiddleware, middleware.RequireScope(utils.WriteArticles), controllers.UpdateArticle)
+	api.Delete("/articles/:slug", middleware.AuthMiddleware, middleware.RequireScope(utils.WriteArticles), controllers.DeleteArticle)
+
+	// Article workflow routes
+	api.Post("/articles/:slug/submit", middleware.AuthMiddleware, middleware.RequireScope(utils.WriteArticles), controllers.SubmitArticle)
+	api.Post("/articles/:slug/approve", middleware.AuthMiddleware, middleware.RequirePermission(utils.ReviewArticles), controllers.ApproveArticle)
+	api.Post("/articles/:slug/reject", middleware.AuthMiddleware, middleware.RequirePermission(utils.ReviewArticles), controllers.RejectArticle)
+	api.Post("/articles/:slug/publish", middleware.AuthMiddleware, middleware.RequireScope(utils.WriteArticles), controllers.PublishArticle)
+	api.Post("/articles/:slug/unpublish", middleware.AuthMiddleware, middleware.RequireScope(utils.WriteArticles), controllers.UnpublishArticle)
+	api.Post("/articles/:slug/schedule", middleware.AuthMiddleware, middleware.RequireScope(utils.WriteArticles), controllers.ScheduleArticle)
+	api.Delete("/articles/:slug/schedule", middleware.AuthMiddleware, middleware.RequireScope(utils.WriteArticles), controllers.UnscheduleArticle)
+
+	// Article revision routes
+	api.Get("/articles/:slug/revisions", middleware.AuthMiddleware, middleware.RequireScope(utils.ReadArticles), controllers.GetArticleRevisions)
+	api.Get("/articles/:slug/revisions/diff", middleware.AuthMiddleware, middleware.RequireScope(utils.ReadArticles), controllers.DiffArticleRevisions)
+	api.Get("/articles/:slug/revisions/:version", middleware.AuthMiddleware, middleware.RequireScope(utils.ReadArticles), controllers.GetArticleRevision)
+	api.Post("/articles/:slug/revisions/:version/restore", middleware.AuthMiddleware, middleware.RequireScope(utils.WriteArticles), controllers.RestoreArticleRevision)
 
 	// Comment routes
-	api.Post("/articles/:slug/comments", middleware.AuthMiddleware, controllers.CreateComment)
-	api.Put("/articles/:slug/comments/:id", middleware.AuthMiddleware, controllers.UpdateComment)
-	api.Delete("/articles/:slug/comments/:id", middleware.AuthMiddleware, controllers.DeleteComment)
+	api.Get("/articles/:slug/comments", controllers.GetArticleComments)
+	api.Get("/articles/:slug/comments/tree", controllers.GetCommentTree)
+	api.Get("/articles/:slug/comments/:id/replies", controllers.GetCommentReplies)
+	api.Post("/articles/:slug/comments", middleware.AuthMiddleware, commentLimit, middleware.RequireVerified, middleware.RequirePermission(utils.WriteComments), controllers.CreateComment)
+	api.Put("/articles/:slug/comments/:id", middleware.AuthMiddleware, commentLimit, middleware.RequireScope(utils.WriteComments), controllers.UpdateComment)
+	api.Delete("/articles/:slug/comments/:id", middleware.AuthMiddleware, middleware.RequireScope(utils.WriteComments), controllers.DeleteComment)
 
 	// Tag routes
 	api.Get("/tags", controllers.GetAllTags)
 	api.Get("/tags/:id", controllers.GetTagById)
-	api.Post("/tags", controllers.CreateTag)
-	api.Put("/tags/:id", controllers.UpdateTag)
-	api.Delete("/tags/:id", controllers.DeleteTag)
+	api.Post("/tags", middleware.AuthMiddleware, middleware.RequirePermission(utils.ManageTags), controllers.CreateTag)
+	api.Put("/tags/:id", middleware.AuthMiddleware, middleware.RequirePermission(utils.ManageTags), controllers.UpdateTag)
+	api.Delete("/tags/:id", middleware.AuthMiddleware, middleware.RequirePermission(utils.ManageTags), controllers.DeleteTag)
+
+	// User routes
+	api.Get("/users/:id/comments", controllers.GetUserComments)
+	api.Put("/users/:id/role", middleware.AuthMiddleware, middleware.RequirePermission(utils.ManageUsers), controllers.UpdateUserRole)
+	api.Put("/users/:id/verify", middleware.AuthMiddleware, middleware.RequirePermission(utils.ManageUsers), controllers.VerifyUser)
+
+	// Audit routes
+	api.Get("/audit", middleware.AuthMiddleware, middleware.RequirePermission(utils.ViewAuditLog), controllers.GetAuditEvents)
+
+	// Login lockout routes
+	api.Get("/lockouts", middleware.AuthMiddleware, middleware.RequirePermission(utils.ManageUsers), controllers.GetLoginLockouts)
+	api.Delete("/lockouts", middleware.AuthMiddleware, middleware.RequirePermission(utils.ManageUsers), controllers.DeleteLoginLockout)
 }
@@ -0,0 +1,234 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDeletionNotScheduled = errors.New("account deletion is not scheduled")
+	ErrReservedEmail        = errors.New("email addresses in the .invalid domain cannot be used")
+	ErrSystemAccount        = errors.New("system accounts cannot sign in")
+	ErrNoDeletedUser        = errors.New("the deleted user placeholder is missing, migrate the database")
+	ErrUnknownRole          = errors.New("role must be admin, editor, author or reader")
+)
+
+// IsReservedEmail reports whether the email is in the .invalid domain reserved for system accounts.
+func IsReservedEmail(email string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSpace(email)), ".invalid")
+}
+
+// SetUserRole changes the role of the user with the email, for example to promote the first admin of a
+// deployment from the command line. The change is audited without an actor.
+func SetUserRole(email string, role entity.UserRole) (*entity.User, error) {
+	if _, ok := RolePermissions[role]; !ok {
+		return nil, ErrUnknownRole
+	}
+
+	var user entity.User
+	if err := database.DB.Where("email = ?", strings.TrimSpace(email)).First(&user).Error; err != nil {
+		return nil, err
+	}
+	if user.IsSystem {
+		return nil, ErrSystemAccount
+	}
+
+	before := AuditSnapshot(user)
+	user.Role = role
+	if err := database.DB.Model(&user).Update("role", user.Role).Error; err != nil {
+		return nil, err
+	}
+	writeAuditEvent(&entity.AuditEvent{
+		Action:     "user_role_update",
+		Outcome:    "success",
+		TargetType: "user",
+		TargetID:   fmt.Sprint(user.ID),
+		Before:     before,
+		After:      AuditSnapshot(user),
+		UserAgent:  "set-role command",
+		CreatedAt:  time.Now(),
+	})
+	return &user, nil
+}
+
+func AccountDeletionGrace() time.Duration {
+	return GetEnvDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour)
+}
+
+// ScheduleAccountDeletion marks the account to be deleted once the grace period has passed.
+func ScheduleAccountDeletion(user *entity.User, mode entity.DeletionMode) error {
+	deleteAt := time.Now().Add(AccountDeletionGrace())
+	if err := database.DB.Model(user).Updates(map[string]interface{}{
+		"deletion_scheduled_at": deleteAt,
+		"deletion_mode":         mode,
+	}).Error; err != nil {
+		return err
+	}
+
+	user.DeletionScheduledAt = &deleteAt
+	user.DeletionMode = mode
+	return nil
+}
+
+// CancelAccountDeletion keeps an account that was scheduled for deletion.
+func CancelAccountDeletion(user *entity.User) error {
+	if user.DeletionScheduledAt == nil {
+		return ErrDeletionNotScheduled
+	}
+
+	if err := database.DB.Model(user).Updates(map[string]interface{}{
+		"deletion_scheduled_at": nil,
+		"deletion_mode":         "",
+	}).Error; err != nil {
+		return err
+	}
+
+	user.DeletionScheduledAt = nil
+	user.DeletionMode = ""
+	return nil
+}
+
+// DeleteAccount deletes the user. Article revisions they made always move to the deleted user placeholder so
+// other authors keep their history; their articles and comments move there as well when anonymizing, and are
+// deleted with the account otherwise, leaving "[deleted]" placeholders for comments that have replies. Everything
+// else the user owns is removed by the cascading foreign keys.
+func DeleteAccount(userID uint, mode entity.DeletionMode) error {
+	var user entity.User
+	var articles []entity.Article
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		placeholder, err := deletedUserPlaceholder(tx)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&entity.ArticleRevision{}).Where("editor_id = ?", user.ID).Update("editor_id", placeholder.ID).Error; err != nil {
+			return err
+		}
+
+		if mode == entity.DeleteContent {
+			if err := tx.Where("author_id = ?", user.ID).Find(&articles).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("author_id = ?", user.ID).Delete(&entity.Article{}).Error; err != nil {
+				return err
+			}
+
+			// Comments others replied to stay as placeholders so the replies are not deleted with them
+			var threaded []uint
+			if err := tx.Model(&entity.Comment{}).
+				Where("user_id = ? AND EXISTS (SELECT 1 FROM comments AS replies WHERE replies.parent_id = comments.id)", user.ID).
+				Pluck("id", &threaded).Error; err != nil {
+				return err
+			}
+			if len(threaded) > 0 {
+				if err := replaceWithPlaceholders(tx, threaded); err != nil {
+					return err
+				}
+			}
+
+			// Delete the rest here rather than through the foreign key, so placeholders left without replies go too
+			var comments []entity.Comment
+			if err := tx.Where("user_id = ?", user.ID).Find(&comments).Error; err != nil {
+				return err
+			}
+			for i := range comments {
+				if err := tx.Delete(&comments[i]).Error; err != nil {
+					return err
+				}
+				if err := prunePlaceholders(tx, comments[i].ParentID); err != nil {
+					return err
+				}
+			}
+		} else {
+			if err := tx.Model(&entity.Article{}).Where("author_id = ?", user.ID).Update("author_id", placeholder.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&entity.Comment{}).Where("user_id = ?", user.ID).Update("user_id", placeholder.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&user).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// Files can only be removed once the rows are gone
+	files := []string{user.Avatar}
+	for _, article := range articles {
+		files = append(files, article.Thumbnail)
+		SyncSearchIndex(article.ID)
+	}
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		if err := DeleteFile(file); err != nil {
+			fmt.Printf("Error deleting file %s: %v\n", file, err)
+		}
+	}
+
+	return nil
+}
+
+// PurgeDeletedAccounts deletes every account whose grace period has passed and returns how many were deleted.
+func PurgeDeletedAccounts() (int, error) {
+	var users []entity.User
+	if err := database.DB.Where("deletion_scheduled_at <= ?", time.Now()).Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, user := range users {
+		if err := DeleteAccount(user.ID, user.DeletionMode); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// StartAccountPurger runs PurgeDeletedAccounts in the background every interval.
+func StartAccountPurger(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			count, err := PurgeDeletedAccounts()
+			if err != nil {
+				fmt.Printf("Error purging deleted accounts: %v\n", err)
+				continue
+			}
+			if count > 0 {
+				fmt.Printf("Deleted %d account(s).\n", count)
+			}
+		}
+	}()
+}
+
+// deletedUserPlaceholder finds the system user that anonymized content is shown under. It is created by
+// the migration and found by its flag, never by its email, so a registered account cannot stand in for it.
+func deletedUserPlaceholder(tx *gorm.DB) (*entity.User, error) {
+	var placeholder entity.User
+	if err := tx.Where("is_system = ? AND email = ?", true, entity.DeletedUserEmail).First(&placeholder).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNoDeletedUser
+		}
+		return nil, err
+	}
+	return &placeholder, nil
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// AccountExport is the personal data of a user, as handed out by the data export.
+type AccountExport struct {
+	ExportedAt time.Time             `json:"exported_at"`
+	Profile    entity.Profile        `json:"profile"`
+	Articles   []entity.Article      `json:"articles"`
+	Comments   []entity.Comment      `json:"comments"`
+	Sessions   []entity.Session      `json:"sessions"`
+	Identities []entity.UserIdentity `json:"identities"`
+	APIKeys    []entity.APIKey       `json:"api_keys"`
+}
+
+// ExportAccount collects the personal data of the user.
+func ExportAccount(userID uint) (*AccountExport, error) {
+	export := &AccountExport{ExportedAt: time.Now()}
+
+	var user entity.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	export.Profile = user.Profile()
+	if err := database.DB.Preload("Category").Preload("Tags").Where("author_id = ?", userID).Order("created_at").Find(&export.Articles).Error; err != nil {
+		return nil, err
+	}
+	if err := database.DB.Where("user_id = ?", userID).Order("created_at").Find(&export.Comments).Error; err != nil {
+		return nil, err
+	}
+	if err := database.DB.Where("user_id = ?", userID).Order("created_at").Find(&export.Sessions).Error; err != nil {
+		return nil, err
+	}
+	if err := database.DB.Where("user_id = ?", userID).Order("created_at").Find(&export.Identities).Error; err != nil {
+		return nil, err
+	}
+	if err := database.DB.Where("user_id = ?", userID).Order("created_at").Find(&export.APIKeys).Error; err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// Files returns the uploaded files that belong to the export: the avatar and the article thumbnails.
+func (export *AccountExport) Files() []string {
+	var files []string
+	if export.Profile.Avatar != "" {
+		files = append(files, export.Profile.Avatar)
+	}
+	for _, article := range export.Articles {
+		if article.Thumbnail != "" {
+			files = append(files, article.Thumbnail)
+		}
+	}
+	return files
+}
+
+// WriteZip writes the export as a ZIP archive with the data in data.json and the uploaded files under files/.
+// Files that no longer exist on disk are left out.
+func (export *AccountExport) WriteZip(w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	data, err := archive.Create("data.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(data)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		return err
+	}
+
+	for _, file := range export.Files() {
+		if err := addZipFile(archive, file); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func addZipFile(archive *zip.Writer, file string) error {
+	source, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	// Keep the upload folders apart, since files in them can share a name
+	target, err := archive.Create(path.Join("files", path.Clean("/"+filepath.ToSlash(file))))
+	if err != nil {
+		return fmt.Errorf("error adding %s to export: %w", file, err)
+	}
+	_, err = io.Copy(target, source)
+	return err
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"go-news-api/models/entity"
+	"go-news-api/models/request"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const articleCommentCount = "(SELECT COUNT(*) FROM comments WHERE comments.article_id = articles.id AND comments.is_deleted = false)"
+
+var ArticlePaginator = Paginator[entity.Article]{
+	IDColumn:     "articles.id",
+	ID:           func(article entity.Article) uint { return article.ID },
+	DefaultSort:  "created_at",
+	DefaultOrder: "desc",
+	Sorts: map[string]SortField[entity.Article]{
+		"created_at": {Column: "articles.created_at", Kind: SortTime, Value: func(article entity.Article) interface{} { return article.CreatedAt }},
+		"updated_at": {Column: "articles.updated_at", Kind: SortTime, Value: func(article entity.Article) interface{} { return article.UpdatedAt }},
+		"title":      {Column: "articles.title", Kind: SortString, Value: func(article entity.Article) interface{} { return article.Title }},
+		"popularity": {Column: articleCommentCount, Kind: SortInt, Value: func(article entity.Article) interface{} { return article.CommentCount }},
+	},
+	Prepare: func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(WithCommentCount).
+			Preload("Category").
+			Preload("Author").
+			Preload("Tags")
+	},
+}
+
+var CategoryPaginator = Paginator[entity.Category]{
+	IDColumn:     "categories.id",
+	ID:           func(category entity.Category) uint { return category.ID },
+	DefaultSort:  "id",
+	DefaultOrder: "asc",
+	Sorts: map[string]SortField[entity.Category]{
+		"id":   {Column: "categories.id", Kind: SortInt, Value: func(category entity.Category) interface{} { return category.ID }},
+		"name": {Column: "categories.name", Kind: SortString, Value: func(category entity.Category) interface{} { return category.Name }},
+	},
+}
+
+var TagPaginator = Paginator[entity.Tag]{
+	IDColumn:     "tags.id",
+	ID:           func(tag entity.Tag) uint { return tag.ID },
+	DefaultSort:  "id",
+	DefaultOrder: "asc",
+	Sorts: map[string]SortField[entity.Tag]{
+		"id":   {Column: "tags.id", Kind: SortInt, Value: func(tag entity.Tag) interface{} { return tag.ID }},
+		"name": {Column: "tags.name", Kind: SortString, Value: func(tag entity.Tag) interface{} { return tag.Name }},
+	},
+}
+
+// WithCommentCount selects the number of comments on each article into Article.CommentCount, without the placeholders of deleted comments.
+func WithCommentCount(db *gorm.DB) *gorm.DB {
+	return db.Select("articles.*, " + articleCommentCount + " AS comment_count")
+}
+
+// FilterArticles applies the filters of an article list request.
+func FilterArticles(req *request.ArticleQueryRequest) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if req.CategoryID != 0 {
+			db = db.Where("articles.category_id = ?", req.CategoryID)
+		}
+		if req.AuthorID != 0 {
+			db = db.Where("articles.author_id = ?", req.AuthorID)
+		}
+		if req.Status != "" {
+			db = db.Where("articles.status = ?", req.Status)
+		}
+		if req.Tag != "" {
+			db = db.Where("articles.id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+				Table("article_tags").
+				Select("article_tags.article_id").
+				Joins("JOIN tags ON tags.id = article_tags.tag_id").
+				Where("tags.name = ?", req.Tag))
+		}
+		if from, err := time.ParseInLocation("2006-01-02", req.From, time.Local); err == nil {
+			db = db.Where("articles.created_at >= ?", from)
+		}
+		if to, err := time.ParseInLocation("2006-01-02", req.To, time.Local); err == nil {
+			db = db.Where("articles.created_at < ?", to.AddDate(0, 0, 1))
+		}
+		return db
+	}
+}
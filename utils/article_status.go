@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"fmt"
+	"go-news-api/models/entity"
+	"time"
+)
+
+// articleTransitions lists the statuses an article may move to from each status.
+var articleTransitions = map[entity.ArticleStatus][]entity.ArticleStatus{
+	entity.Draft:     {entity.Review},
+	entity.Review:    {entity.Approved, entity.Draft},
+	entity.Approved:  {entity.Published, entity.Draft},
+	entity.Published: {entity.Draft},
+}
+
+func CanTransitionArticle(from, to entity.ArticleStatus) bool {
+	for _, status := range articleTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionArticle moves the article to the given status, or returns an error
+// if the change is not allowed from its current status.
+func TransitionArticle(article *entity.Article, to entity.ArticleStatus) error {
+	if !CanTransitionArticle(article.Status, to) {
+		return fmt.Errorf("cannot change article status from %s to %s", article.Status, to)
+	}
+
+	article.Status = to
+
+	switch to {
+	case entity.Review, entity.Approved:
+		article.RejectionReason = ""
+	case entity.Published:
+		// A schedule left on the article would hide it until then, even when it is published by hand
+		now := time.Now()
+		article.PublishAt = nil
+		article.PublishedAt = &now
+	case entity.Draft:
+		article.PublishAt = nil
+		article.PublishedAt = nil
+	}
+
+	return nil
+}
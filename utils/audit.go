@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"go-news-api/models/request"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// AuditRecord is the internal record of what really happened on a request whose response is kept uniform.
+// UserID and Email are the account the record is about, the actor is the signed in user making the request, if any.
+type AuditRecord struct {
+	Action     string    `json:"action"`
+	Outcome    string    `json:"outcome"`
+	UserID     uint      `json:"user_id,omitempty"`
+	Email      string    `json:"email,omitempty"`
+	ActorID    uint      `json:"actor_id,omitempty"`
+	ActorEmail string    `json:"actor_email,omitempty"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	Time       time.Time `json:"time"`
+}
+
+// Audit writes an audit record for the request. It must be called before the handler returns,
+// because fiber reuses the context afterwards.
+func Audit(ctx *fiber.Ctx, action, outcome string, userID uint, email string) {
+	WriteAudit(NewAuditRecord(ctx, action, outcome, userID, email))
+}
+
+// NewAuditRecord prepares an audit record from the request without writing it.
+func NewAuditRecord(ctx *fiber.Ctx, action, outcome string, userID uint, email string) AuditRecord {
+	record := AuditRecord{
+		Action:    action,
+		Outcome:   outcome,
+		UserID:    userID,
+		Email:     email,
+		IPAddress: ctx.IP(),
+		UserAgent: ctx.Get(fiber.HeaderUserAgent),
+		Time:      time.Now(),
+	}
+	if user, ok := ctx.Locals("user").(*entity.User); ok && user != nil {
+		record.ActorID = user.ID
+		record.ActorEmail = user.Email
+	}
+	return record
+}
+
+// WriteAudit writes a prepared audit record, for work that finishes after the request.
+// The account the record is about is its target, by ID when it is known and by email otherwise.
+// Requests made without signing in, such as a failed login, have no actor.
+func WriteAudit(record AuditRecord) {
+	event := entity.AuditEvent{
+		ActorEmail: record.ActorEmail,
+		Action:     record.Action,
+		Outcome:    record.Outcome,
+		IPAddress:  record.IPAddress,
+		UserAgent:  record.UserAgent,
+		CreatedAt:  record.Time,
+	}
+	if record.ActorID != 0 {
+		actorID := record.ActorID
+		event.ActorID = &actorID
+	}
+	switch {
+	case record.UserID != 0:
+		event.TargetType = "user"
+		event.TargetID = strconv.FormatUint(uint64(record.UserID), 10)
+	case record.Email != "":
+		event.TargetType = "email"
+		event.TargetID = record.Email
+	}
+	writeAuditEvent(&event)
+}
+
+// AuditChange records an action the signed in user took on a resource, with snapshots of the resource
+// before and after it. Pass nil for a snapshot that does not exist, and take the before snapshot with
+// AuditSnapshot when the resource is changed in place. Like Audit, it must be called before the handler returns.
+func AuditChange(ctx *fiber.Ctx, action, targetType string, targetID interface{}, before, after interface{}) {
+	event := entity.AuditEvent{
+		Action:     action,
+		Outcome:    "success",
+		TargetType: targetType,
+		TargetID:   fmt.Sprint(targetID),
+		Before:     AuditSnapshot(before),
+		After:      AuditSnapshot(after),
+		IPAddress:  ctx.IP(),
+		UserAgent:  ctx.Get(fiber.HeaderUserAgent),
+		CreatedAt:  time.Now(),
+	}
+	if user, ok := ctx.Locals("user").(*entity.User); ok && user != nil {
+		actorID := user.ID
+		event.ActorID = &actorID
+		event.ActorEmail = user.Email
+	}
+	writeAuditEvent(&event)
+}
+
+// AuditSnapshot captures the JSON of a resource as it is now.
+func AuditSnapshot(resource interface{}) json.RawMessage {
+	if resource == nil {
+		return nil
+	}
+	if snapshot, ok := resource.(json.RawMessage); ok {
+		return snapshot
+	}
+
+	snapshot, err := json.Marshal(resource)
+	if err != nil {
+		fmt.Printf("Error taking audit snapshot: %v\n", err)
+		return nil
+	}
+	if string(snapshot) == "null" {
+		return nil
+	}
+	return snapshot
+}
+
+// writeAuditEvent stores the event, falling back to the log so an event is never lost silently.
+func writeAuditEvent(event *entity.AuditEvent) {
+	if len(event.UserAgent) > 255 {
+		event.UserAgent = event.UserAgent[:255]
+	}
+
+	if err := database.DB.Create(event).Error; err != nil {
+		line, _ := json.Marshal(event)
+		fmt.Printf("Error storing audit event: %v\n[audit] %s\n", err, line)
+	}
+}
+
+var AuditEventPaginator = Paginator[entity.AuditEvent]{
+	IDColumn:     "audit_events.id",
+	ID:           func(event entity.AuditEvent) uint { return event.ID },
+	DefaultSort:  "created_at",
+	DefaultOrder: "desc",
+	Sorts: map[string]SortField[entity.AuditEvent]{
+		"created_at": {Column: "audit_events.created_at", Kind: SortTime, Value: func(event entity.AuditEvent) interface{} { return event.CreatedAt }},
+	},
+}
+
+// FilterAuditEvents applies the filters of an audit log request.
+func FilterAuditEvents(req *request.AuditQueryRequest) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if req.ActorID != 0 {
+			db = db.Where("audit_events.actor_id = ?", req.ActorID)
+		}
+		if req.Action != "" {
+			db = db.Where("audit_events.action = ?", req.Action)
+		}
+		if req.Outcome != "" {
+			db = db.Where("audit_events.outcome = ?", req.Outcome)
+		}
+		if req.TargetType != "" {
+			db = db.Where("audit_events.target_type = ?", req.TargetType)
+		}
+		if req.TargetID != "" {
+			db = db.Where("audit_events.target_id = ?", req.TargetID)
+		}
+		if from, err := time.ParseInLocation("2006-01-02", req.From, time.Local); err == nil {
+			db = db.Where("audit_events.created_at >= ?", from)
+		}
+		if to, err := time.ParseInLocation("2006-01-02", req.To, time.Local); err == nil {
+			db = db.Where("audit_events.created_at < ?", to.AddDate(0, 0, 1))
+		}
+		return db
+	}
+}
+
+// ExportAuditEvents writes the matching events, oldest first, as JSON lines or CSV and returns how many were written.
+// Events are read in batches so large logs do not have to fit in memory.
+func ExportAuditEvents(w io.Writer, req *request.AuditQueryRequest, format string) (int, error) {
+	var write func(event entity.AuditEvent) error
+	var flush func() error
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		write = func(event entity.AuditEvent) error { return encoder.Encode(event) }
+		flush = func() error { return nil }
+	case "csv":
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"id", "created_at", "actor_id", "actor_email", "action", "outcome", "target_type", "target_id", "before", "after", "ip_address", "user_agent"}); err != nil {
+			return 0, err
+		}
+		write = func(event entity.AuditEvent) error {
+			actorID := ""
+			if event.ActorID != nil {
+				actorID = strconv.FormatUint(uint64(*event.ActorID), 10)
+			}
+			return writer.Write([]string{
+				strconv.FormatUint(uint64(event.ID), 10), event.CreatedAt.Format(time.RFC3339), actorID, event.ActorEmail,
+				event.Action, event.Outcome, event.TargetType, event.TargetID,
+				string(event.Before), string(event.After), event.IPAddress, event.UserAgent,
+			})
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	default:
+		return 0, fmt.Errorf("unknown export format %q, use json or csv", format)
+	}
+
+	count := 0
+	var events []entity.AuditEvent
+	err := database.DB.Model(&entity.AuditEvent{}).Scopes(FilterAuditEvents(req)).Order("audit_events.id").
+		FindInBatches(&events, 500, func(tx *gorm.DB, batch int) error {
+			for _, event := range events {
+				if err := write(event); err != nil {
+					return err
+				}
+				count++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return count, err
+	}
+
+	return count, flush()
+}
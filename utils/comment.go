@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DeletedCommentContent replaces the content of a deleted comment that still has replies.
+	DeletedCommentContent = "[deleted]"
+	// DefaultRepliesPerPage is how many replies of each comment a comment tree includes by default.
+	DefaultRepliesPerPage = 3
+)
+
+const commentReplyCount = "(SELECT COUNT(*) FROM comments AS replies WHERE replies.parent_id = comments.id)"
+
+var (
+	ErrParentCommentNotFound = errors.New("parent comment not found")
+	ErrParentCommentDeleted  = errors.New("cannot reply to a deleted comment")
+	ErrCommentTooDeep        = errors.New("replies cannot be nested any deeper")
+	ErrCommentDeleted        = errors.New("comment has been deleted")
+)
+
+// CommentPaginator sorts comments by newest, oldest or top, top being the comments with the most replies.
+var CommentPaginator = Paginator[entity.Comment]{
+	IDColumn:     "comments.id",
+	ID:           func(comment entity.Comment) uint { return comment.ID },
+	DefaultSort:  "oldest",
+	DefaultOrder: "asc",
+	Sorts: map[string]SortField[entity.Comment]{
+		"newest": {Column: "comments.created_at", Kind: SortTime, Value: func(comment entity.Comment) interface{} { return comment.CreatedAt }, Order: "desc"},
+		"oldest": {Column: "comments.created_at", Kind: SortTime, Value: func(comment entity.Comment) interface{} { return comment.CreatedAt }, Order: "asc"},
+		"top":    {Column: commentReplyCount, Kind: SortInt, Value: func(comment entity.Comment) interface{} { return comment.ReplyCount }, Order: "desc"},
+	},
+	Prepare: func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(WithReplyCount).Preload("User")
+	},
+}
+
+// CommentMaxDepth is how deeply replies can be nested. Top-level comments are at depth 0.
+func CommentMaxDepth() int {
+	return GetEnvInt("COMMENT_MAX_DEPTH", 5)
+}
+
+// VisibleComments leaves out the placeholders of deleted comments.
+func VisibleComments(db *gorm.DB) *gorm.DB {
+	return db.Where("comments.is_deleted = ?", false)
+}
+
+// WithReplyCount selects the number of direct replies to each comment into Comment.ReplyCount.
+func WithReplyCount(db *gorm.DB) *gorm.DB {
+	return db.Select("comments.*, " + commentReplyCount + " AS reply_count")
+}
+
+// ReplyParent finds the comment a new comment on the article replies to and checks it can take another reply.
+func ReplyParent(articleID, parentID uint) (*entity.Comment, error) {
+	var parent entity.Comment
+	if err := database.DB.First(&parent, "id = ? AND article_id = ?", parentID, articleID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrParentCommentNotFound
+		}
+		return nil, err
+	}
+
+	if parent.IsDeleted {
+		return nil, ErrParentCommentDeleted
+	}
+	if parent.Depth+1 > CommentMaxDepth() {
+		return nil, ErrCommentTooDeep
+	}
+
+	return &parent, nil
+}
+
+// DeleteComment deletes the comment. A comment with replies is replaced by a placeholder owned by the deleted
+// user so the thread stays intact, and placeholders that no longer have replies are removed with their last reply.
+func DeleteComment(comment *entity.Comment) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var replies int64
+		if err := tx.Model(&entity.Comment{}).Where("parent_id = ?", comment.ID).Count(&replies).Error; err != nil {
+			return err
+		}
+		if replies > 0 {
+			return replaceWithPlaceholders(tx, []uint{comment.ID})
+		}
+
+		if err := tx.Delete(comment).Error; err != nil {
+			return err
+		}
+		return prunePlaceholders(tx, comment.ParentID)
+	})
+}
+
+// replaceWithPlaceholders turns the comments into "[deleted]" placeholders.
+func replaceWithPlaceholders(tx *gorm.DB, ids []uint) error {
+	placeholder, err := deletedUserPlaceholder(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&entity.Comment{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"content":    DeletedCommentContent,
+		"user_id":    placeholder.ID,
+		"is_deleted": true,
+	}).Error
+}
+
+// prunePlaceholders walks up from parentID and deletes placeholders left without replies.
+func prunePlaceholders(tx *gorm.DB, parentID *uint) error {
+	for parentID != nil {
+		var parent entity.Comment
+		if err := tx.First(&parent, *parentID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil
+			}
+			return err
+		}
+		if !parent.IsDeleted {
+			return nil
+		}
+
+		var replies int64
+		if err := tx.Model(&entity.Comment{}).Where("parent_id = ?", parent.ID).Count(&replies).Error; err != nil {
+			return err
+		}
+		if replies > 0 {
+			return nil
+		}
+
+		if err := tx.Delete(&parent).Error; err != nil {
+			return err
+		}
+		parentID = parent.ParentID
+	}
+
+	return nil
+}
+
+// LoadReplies fills in the replies of the comments, down to depth levels below them. Each comment gets at most
+// perPage of its oldest replies, the rest can be paged through with the replies endpoint using ReplyCount.
+func LoadReplies(comments []entity.Comment, depth, perPage int) error {
+	level := make([]*entity.Comment, len(comments))
+	for i := range comments {
+		level[i] = &comments[i]
+	}
+
+	for ; depth > 0 && len(level) > 0; depth-- {
+		parents := make(map[uint]*entity.Comment)
+		var ids []uint
+		for _, comment := range level {
+			if comment.ReplyCount > 0 {
+				parents[comment.ID] = comment
+				ids = append(ids, comment.ID)
+			}
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		// Rank the replies of each parent so only the first page of every parent is fetched
+		ranked := database.DB.Model(&entity.Comment{}).
+			Select("comments.*, "+commentReplyCount+" AS reply_count, ROW_NUMBER() OVER (PARTITION BY comments.parent_id ORDER BY comments.created_at, comments.id) AS reply_rank").
+			Where("comments.parent_id IN ?", ids)
+
+		var replies []entity.Comment
+		if err := database.DB.Table("(?) AS comments", ranked).
+			Where("comments.reply_rank <= ?", perPage).
+			Order("comments.created_at, comments.id").
+			Preload("User").
+			Find(&replies).Error; err != nil {
+			return err
+		}
+
+		for _, reply := range replies {
+			parent := parents[*reply.ParentID]
+			parent.Replies = append(parent.Replies, reply)
+		}
+
+		// Descend once every parent has all of its replies, appending may move them
+		var next []*entity.Comment
+		for _, id := range ids {
+			parent := parents[id]
+			for i := range parent.Replies {
+				next = append(next, &parent.Replies[i])
+			}
+		}
+		level = next
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+type DiffOperation string
+
+const (
+	DiffEqual  DiffOperation = "equal"
+	DiffInsert DiffOperation = "insert"
+	DiffDelete DiffOperation = "delete"
+)
+
+// MaxDiffLines is how many lines each side of a diff may have. Diffing takes time proportional to the
+// number of lines times the number of changes, so larger texts are refused.
+const MaxDiffLines = 5000
+
+var ErrDiffTooLarge = errors.New("text is too long to compare")
+
+type DiffLine struct {
+	Operation DiffOperation `json:"operation"`
+	Text      string        `json:"text"`
+}
+
+// DiffLines returns a shortest line-level diff that turns from into to. It uses Myers' algorithm in
+// linear space, bisecting on the middle snake of the edit graph.
+func DiffLines(from, to string) ([]DiffLine, error) {
+	a := splitLines(from)
+	b := splitLines(to)
+	if len(a) > MaxDiffLines || len(b) > MaxDiffLines {
+		return nil, ErrDiffTooLarge
+	}
+
+	// Compare lines by number rather than by text
+	ids := make(map[string]int)
+	number := func(lines []string) []int {
+		numbers := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			numbers[i] = id
+		}
+		return numbers
+	}
+
+	d := &differ{from: a, to: b, a: number(a), b: number(b)}
+	d.compare(0, len(a), 0, len(b))
+	return d.diff, nil
+}
+
+type differ struct {
+	from, to []string
+	a, b     []int
+	diff     []DiffLine
+}
+
+// compare appends the diff of a[aLo:aHi] and b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	// Common prefix and suffix
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.diff = append(d.diff, DiffLine{Operation: DiffEqual, Text: d.from[aLo]})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-1-suffix] == d.b[bHi-1-suffix] {
+		suffix++
+	}
+	aHi, bHi = aHi-suffix, bHi-suffix
+
+	switch {
+	case aLo == aHi:
+		for ; bLo < bHi; bLo++ {
+			d.diff = append(d.diff, DiffLine{Operation: DiffInsert, Text: d.to[bLo]})
+		}
+	case bLo == bHi:
+		for ; aLo < aHi; aLo++ {
+			d.diff = append(d.diff, DiffLine{Operation: DiffDelete, Text: d.from[aLo]})
+		}
+	default:
+		if x, y, ok := d.bisect(aLo, aHi, bLo, bHi); ok {
+			d.compare(aLo, x, bLo, y)
+			d.compare(x, aHi, y, bHi)
+		} else {
+			for i := aLo; i < aHi; i++ {
+				d.diff = append(d.diff, DiffLine{Operation: DiffDelete, Text: d.from[i]})
+			}
+			for j := bLo; j < bHi; j++ {
+				d.diff = append(d.diff, DiffLine{Operation: DiffInsert, Text: d.to[j]})
+			}
+		}
+	}
+
+	for i := aHi; i < aHi+suffix; i++ {
+		d.diff = append(d.diff, DiffLine{Operation: DiffEqual, Text: d.from[i]})
+	}
+}
+
+// bisect walks shortest edit paths forwards from the start and backwards from the end of a[aLo:aHi] and
+// b[bLo:bHi] until they overlap, and returns the point where they meet. It reports false when the two have
+// no line in common.
+func (d *differ) bisect(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	n, m := aHi-aLo, bHi-bLo
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+
+	// forward[k] and backward[k] are the furthest x reached on diagonal k, counted from either end
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	odd := delta%2 != 0
+	kStartF, kEndF, kStartB, kEndB := 0, 0, 0, 0
+
+	for step := 0; step <= maxD; step++ {
+		for k := -step + kStartF; k <= step-kEndF; k += 2 {
+			var x int
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			switch {
+			case x > n:
+				kEndF += 2
+			case y > m:
+				kStartF += 2
+			case odd:
+				if kb := offset + delta - k; kb >= 0 && kb < len(backward) && backward[kb] != -1 && x >= n-backward[kb] {
+					return aLo + x, bLo + y, true
+				}
+			}
+		}
+
+		for k := -step + kStartB; k <= step-kEndB; k += 2 {
+			var x int
+			if k == -step || (k != step && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			switch {
+			case x > n:
+				kEndB += 2
+			case y > m:
+				kStartB += 2
+			case !odd:
+				if kf := offset + delta - k; kf >= 0 && kf < len(forward) && forward[kf] != -1 {
+					fx := forward[kf]
+					fy := fx - (kf - offset)
+					if fx >= n-x {
+						return aLo + fx, bLo + fy, true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []DiffLine
+	}{
+		{name: "both empty", from: "", to: "", want: nil},
+		{name: "added text", from: "", to: "a\nb", want: []DiffLine{{DiffInsert, "a"}, {DiffInsert, "b"}}},
+		{name: "removed text", from: "a\nb", to: "", want: []DiffLine{{DiffDelete, "a"}, {DiffDelete, "b"}}},
+		{name: "unchanged", from: "a\nb", to: "a\r\nb", want: []DiffLine{{DiffEqual, "a"}, {DiffEqual, "b"}}},
+		{name: "changed line", from: "a\nb\nc", to: "a\nx\nc", want: []DiffLine{{DiffEqual, "a"}, {DiffDelete, "b"}, {DiffInsert, "x"}, {DiffEqual, "c"}}},
+		{name: "nothing in common", from: "a\nb", to: "c\nd", want: []DiffLine{{DiffDelete, "a"}, {DiffDelete, "b"}, {DiffInsert, "c"}, {DiffInsert, "d"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := DiffLines(test.from, test.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("DiffLines(%q, %q) = %v, want %v", test.from, test.to, got, test.want)
+			}
+		})
+	}
+
+	t.Run("finds a shortest diff", func(t *testing.T) {
+		random := rand.New(rand.NewSource(1))
+		text := func() string {
+			lines := make([]string, random.Intn(30))
+			for i := range lines {
+				lines[i] = string(rune('a' + random.Intn(4)))
+			}
+			return strings.Join(lines, "\n")
+		}
+
+		for i := 0; i < 500; i++ {
+			from, to := text(), text()
+			diff, err := DiffLines(from, to)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var gotFrom, gotTo []string
+			equal := 0
+			for _, line := range diff {
+				if line.Operation != DiffInsert {
+					gotFrom = append(gotFrom, line.Text)
+				}
+				if line.Operation != DiffDelete {
+					gotTo = append(gotTo, line.Text)
+				}
+				if line.Operation == DiffEqual {
+					equal++
+				}
+			}
+			if strings.Join(gotFrom, "\n") != from || strings.Join(gotTo, "\n") != to {
+				t.Fatalf("DiffLines(%q, %q) = %v does not rebuild both texts", from, to, diff)
+			}
+			if want := longestCommonLines(splitLines(from), splitLines(to)); equal != want {
+				t.Fatalf("DiffLines(%q, %q) keeps %d lines, want %d", from, to, equal, want)
+			}
+		}
+	})
+
+	t.Run("refuses oversized texts", func(t *testing.T) {
+		long := strings.Repeat("line\n", MaxDiffLines)
+		if _, err := DiffLines(long, ""); !errors.Is(err, ErrDiffTooLarge) {
+			t.Errorf("expected ErrDiffTooLarge, got %v", err)
+		}
+	})
+}
+
+func longestCommonLines(a, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	return lengths[0][0]
+}
@@ -3,7 +3,6 @@ package utils
 import (
 	"bytes"
 	"fmt"
-	"math/rand"
 	"net/smtp"
 	"os"
 	"text/template"
@@ -11,17 +10,6 @@ import (
 	"github.com/joho/godotenv"
 )
 
-func GenerateOTP(length int) string {
-	var otp string
-
-	for i := 0; i < length; i++ {
-		digit := rand.Intn(10)
-		otp += fmt.Sprintf("%d", digit)
-	}
-
-	return otp
-}
-
 func SendEmail(to string, subject string, templateFile string, data interface{}) error {
 	// Load environment variables
 	err := godotenv.Load()
@@ -2,27 +2,37 @@ package utils
 
 import (
 	"errors"
-	"os"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtKey = []byte(os.Getenv("JWT_KEY"))
-
+// GenerateToken signs the claims with the current signing key and names the key in the kid header.
 func GenerateToken(claims *jwt.MapClaims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	jwt, err := token.SignedString(jwtKey)
+	key, err := currentSigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	jwt, err := token.SignedString(key.private)
 	if err != nil {
 		return "", err
 	}
 	return jwt, nil
 }
 
+// ParseToken verifies a token with the key named in its kid header, which must not have retired.
 func ParseToken(tokenString string) (*jwt.Token, error) {
 	return jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := t.Header["kid"].(string)
+		key, err := lookupSigningKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != key.method.Alg() {
 			return nil, errors.New("unexpected signing method")
 		}
-		return jwtKey, nil
-	})
+		return key.private.Public(), nil
+	}, jwt.WithValidMethods([]string{SigningAlgorithmRS256, SigningAlgorithmEdDSA}))
 }
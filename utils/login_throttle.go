@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"go-news-api/models/entity"
+	"math"
+	"strings"
+	"time"
+)
+
+var ErrInvalidUnlockToken = errors.New("invalid or expired unlock token")
+
+// LoginAttemptStore keeps failed login state. The memory store suits a single node,
+// the database store shares the state between several nodes.
+type LoginAttemptStore interface {
+	// Get returns the state under key, or nil when there is none.
+	Get(key string) (*entity.LoginAttempt, error)
+	// Update runs apply on the state under key, creating it when missing, and saves the result atomically.
+	Update(key string, apply func(attempt *entity.LoginAttempt)) (*entity.LoginAttempt, error)
+	Delete(key string) error
+	// Active lists the states that currently block or lock logins.
+	Active(now time.Time) ([]entity.LoginAttempt, error)
+}
+
+// LoginAttempts is the store used by login. It is replaced by SetupLoginThrottle.
+var LoginAttempts LoginAttemptStore = NewMemoryLoginAttemptStore()
+
+// LoginThrottledError is returned when a login is refused because of earlier failures.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return "account is temporarily locked because of too many failed logins, check your email to unlock it"
+	}
+	return "too many failed logins, please try again later"
+}
+
+// SetupLoginThrottle selects the login attempt store by driver name, "memory" or "database".
+func SetupLoginThrottle(driver string) error {
+	switch driver {
+	case "", "memory":
+		LoginAttempts = NewMemoryLoginAttemptStore()
+	case "database":
+		LoginAttempts = DatabaseLoginAttemptStore{}
+	default:
+		return fmt.Errorf("unknown login throttle store %q", driver)
+	}
+	return nil
+}
+
+func AccountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func IPThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// CheckLoginAllowed refuses a login while the account or the IP address is backing off or locked.
+func CheckLoginAllowed(email, ip string) error {
+	now := time.Now()
+	for _, key := range []string{AccountThrottleKey(email), IPThrottleKey(ip)} {
+		attempt, err := LoginAttempts.Get(key)
+		if err != nil {
+			return err
+		}
+		if attempt == nil {
+			continue
+		}
+
+		if attempt.LockedUntil != nil && now.Before(*attempt.LockedUntil) {
+			return &LoginThrottledError{RetryAfter: attempt.LockedUntil.Sub(now), Locked: true}
+		}
+		if attempt.BlockedUntil != nil && now.Before(*attempt.BlockedUntil) {
+			return &LoginThrottledError{RetryAfter: attempt.BlockedUntil.Sub(now)}
+		}
+	}
+	return nil
+}
+
+// RecordLoginFailure counts a failed login against the account and the IP address. When the failure locks
+// the account, it returns the unlock token that has to be sent to the account owner.
+func RecordLoginFailure(email, ip string) (string, error) {
+	now := time.Now()
+	threshold := GetEnvInt("LOGIN_LOCKOUT_THRESHOLD", 10)
+
+	var unlockToken string
+	_, err := LoginAttempts.Update(AccountThrottleKey(email), func(attempt *entity.LoginAttempt) {
+		countLoginFailure(attempt, now, GetEnvInt("LOGIN_BACKOFF_THRESHOLD", 3))
+
+		if attempt.Failures >= threshold && attempt.LockedUntil == nil {
+			token, err := RandomToken(32)
+			if err != nil {
+				return
+			}
+			lockedUntil := now.Add(GetEnvDuration("LOGIN_LOCKOUT_DURATION", 30*time.Minute))
+			attempt.LockedUntil = &lockedUntil
+			attempt.UnlockTokenHash = HashToken(token)
+			unlockToken = token
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+
+	_, err = LoginAttempts.Update(IPThrottleKey(ip), func(attempt *entity.LoginAttempt) {
+		countLoginFailure(attempt, now, GetEnvInt("LOGIN_IP_BACKOFF_THRESHOLD", 10))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return unlockToken, nil
+}
+
+// ResetLoginFailures forgets the failures of the account after a successful login.
+// The IP address keeps its failures, so one good login cannot hide a credential stuffing run.
+func ResetLoginFailures(email string) error {
+	return LoginAttempts.Delete(AccountThrottleKey(email))
+}
+
+// UnlockAccount lifts a lockout with the token sent in the lockout email.
+func UnlockAccount(email, token string) error {
+	key := AccountThrottleKey(email)
+	attempt, err := LoginAttempts.Get(key)
+	if err != nil {
+		return err
+	}
+	if attempt == nil || attempt.LockedUntil == nil || attempt.UnlockTokenHash == "" ||
+		time.Now().After(*attempt.LockedUntil) || attempt.UnlockTokenHash != HashToken(token) {
+		return ErrInvalidUnlockToken
+	}
+
+	return LoginAttempts.Delete(key)
+}
+
+// countLoginFailure adds a failure and backs off exponentially once the threshold is reached.
+// Failures older than the attempt window and expired lockouts start the count over.
+func countLoginFailure(attempt *entity.LoginAttempt, now time.Time, threshold int) {
+	expiredLock := attempt.LockedUntil != nil && !now.Before(*attempt.LockedUntil)
+	if expiredLock || now.Sub(attempt.LastFailureAt) > GetEnvDuration("LOGIN_ATTEMPT_WINDOW", time.Hour) {
+		attempt.Failures = 0
+		attempt.BlockedUntil = nil
+		attempt.LockedUntil = nil
+		attempt.UnlockTokenHash = ""
+	}
+
+	attempt.Failures++
+	attempt.LastFailureAt = now
+
+	if attempt.Failures >= threshold {
+		delay := loginBackoff(attempt.Failures - threshold)
+		blockedUntil := now.Add(delay)
+		attempt.BlockedUntil = &blockedUntil
+	}
+}
+
+// loginBackoff doubles the base delay for every failure past the threshold, up to the maximum.
+func loginBackoff(step int) time.Duration {
+	base := GetEnvDuration("LOGIN_BACKOFF_BASE", time.Second)
+	max := GetEnvDuration("LOGIN_BACKOFF_MAX", 15*time.Minute)
+
+	delay := float64(base) * math.Pow(2, float64(step))
+	if delay > float64(max) {
+		return max
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DatabaseLoginAttemptStore keeps login attempt state in the login_attempts table, shared by every node.
+type DatabaseLoginAttemptStore struct{}
+
+func (DatabaseLoginAttemptStore) Get(key string) (*entity.LoginAttempt, error) {
+	var attempt entity.LoginAttempt
+	err := database.DB.Where("throttle_key = ?", key).First(&attempt).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (DatabaseLoginAttemptStore) Update(key string, apply func(attempt *entity.LoginAttempt)) (*entity.LoginAttempt, error) {
+	var attempt entity.LoginAttempt
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Make sure the row exists, then lock it for the read-modify-write
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&entity.LoginAttempt{Key: key, LastFailureAt: time.Now()}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("throttle_key = ?", key).First(&attempt).Error; err != nil {
+			return err
+		}
+
+		apply(&attempt)
+		return tx.Save(&attempt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (DatabaseLoginAttemptStore) Delete(key string) error {
+	return database.DB.Where("throttle_key = ?", key).Delete(&entity.LoginAttempt{}).Error
+}
+
+func (DatabaseLoginAttemptStore) Active(now time.Time) ([]entity.LoginAttempt, error) {
+	var attempts []entity.LoginAttempt
+	err := database.DB.Where("locked_until > ? OR blocked_until > ?", now, now).
+		Order("updated_at DESC").Find(&attempts).Error
+	return attempts, err
+}
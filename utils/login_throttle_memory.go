@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"go-news-api/models/entity"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryLoginAttemptStore keeps login attempt state in process memory.
+type MemoryLoginAttemptStore struct {
+	mu        sync.Mutex
+	attempts  map[string]entity.LoginAttempt
+	lastPrune time.Time
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{attempts: map[string]entity.LoginAttempt{}}
+}
+
+func (s *MemoryLoginAttemptStore) Get(key string) (*entity.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (s *MemoryLoginAttemptStore) Update(key string, apply func(attempt *entity.LoginAttempt)) (*entity.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.prune(now)
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = entity.LoginAttempt{Key: key, LastFailureAt: now}
+	}
+	apply(&attempt)
+	attempt.UpdatedAt = now
+	s.attempts[key] = attempt
+
+	return &attempt, nil
+}
+
+func (s *MemoryLoginAttemptStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) Active(now time.Time) ([]entity.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts := []entity.LoginAttempt{}
+	for _, attempt := range s.attempts {
+		if loginAttemptActive(attempt, now) {
+			attempts = append(attempts, attempt)
+		}
+	}
+	sort.Slice(attempts, func(i, j int) bool {
+		return attempts[i].UpdatedAt.After(attempts[j].UpdatedAt)
+	})
+	return attempts, nil
+}
+
+// prune drops state that no longer blocks anything and is outside the attempt window, at most once a minute.
+func (s *MemoryLoginAttemptStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < time.Minute {
+		return
+	}
+	s.lastPrune = now
+
+	window := GetEnvDuration("LOGIN_ATTEMPT_WINDOW", time.Hour)
+	for key, attempt := range s.attempts {
+		if !loginAttemptActive(attempt, now) && now.Sub(attempt.LastFailureAt) > window {
+			delete(s.attempts, key)
+		}
+	}
+}
+
+func loginAttemptActive(attempt entity.LoginAttempt, now time.Time) bool {
+	return (attempt.LockedUntil != nil && now.Before(*attempt.LockedUntil)) ||
+		(attempt.BlockedUntil != nil && now.Before(*attempt.BlockedUntil))
+}
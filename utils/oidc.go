@@ -0,0 +1,327 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownProvider     = errors.New("unknown identity provider")
+	ErrInvalidOAuthState   = errors.New("invalid or expired login state")
+	ErrEmailNotVerified    = errors.New("the identity provider has not verified this email")
+	ErrIdentityLinked      = errors.New("this external account is already linked to another user")
+	ErrProviderAlreadyUsed = errors.New("a different account of this provider is already linked")
+	ErrLastLoginMethod     = errors.New("cannot unlink the only way to sign in, set a password first")
+	ErrUnverifiedAccount   = errors.New("an account with this email exists but has not verified it, sign in with its password and link the provider from your profile")
+)
+
+// OAuthStateTTL is how long a user has to finish signing in at the provider.
+const OAuthStateTTL = 10 * time.Minute
+
+// OIDCBindingCookie holds the secret that ties an authorization request to the browser that started it.
+const OIDCBindingCookie = "oidc_binding"
+
+// OIDCClaims are the claims read from a verified ID token.
+type OIDCClaims struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// OIDCClient is a configured provider with its discovered endpoints.
+type OIDCClient struct {
+	Name     string
+	Config   oauth2.Config
+	Verifier *oidc.IDTokenVerifier
+}
+
+var (
+	oidcClients   = map[string]*OIDCClient{}
+	oidcClientsMu sync.Mutex
+)
+
+// OIDCProviders returns the names of the configured providers, from OIDC_PROVIDERS.
+func OIDCProviders() []string {
+	var providers []string
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			providers = append(providers, name)
+		}
+	}
+	return providers
+}
+
+// GetOIDCClient returns the client of a configured provider. Its endpoints are discovered from
+// OIDC_<NAME>_ISSUER on first use, so a provider that is down does not stop the server from starting.
+func GetOIDCClient(ctx context.Context, name string) (*OIDCClient, error) {
+	name = strings.ToLower(name)
+
+	configured := false
+	for _, provider := range OIDCProviders() {
+		if provider == name {
+			configured = true
+		}
+	}
+	if !configured {
+		return nil, ErrUnknownProvider
+	}
+
+	oidcClientsMu.Lock()
+	defer oidcClientsMu.Unlock()
+
+	if client, ok := oidcClients[name]; ok {
+		return client, nil
+	}
+
+	prefix := "OIDC_" + strings.ToUpper(name) + "_"
+	provider, err := oidc.NewProvider(ctx, os.Getenv(prefix+"ISSUER"))
+	if err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", name, err)
+	}
+
+	scopes := []string{oidc.ScopeOpenID, "email", "profile"}
+	if value := os.Getenv(prefix + "SCOPES"); value != "" {
+		scopes = strings.Fields(strings.ReplaceAll(value, ",", " "))
+	}
+
+	clientID := os.Getenv(prefix + "CLIENT_ID")
+	client := &OIDCClient{
+		Name: name,
+		Config: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		Verifier: provider.Verifier(&oidc.Config{ClientID: clientID}),
+	}
+	oidcClients[name] = client
+
+	return client, nil
+}
+
+// StartOIDCFlow stores a new state, nonce and PKCE verifier and returns the provider's authorization URL, together
+// with a binding secret for the browser to keep in the OIDCBindingCookie. When linkUserID is set, the callback links
+// the external account to that user instead of signing in.
+func StartOIDCFlow(ctx context.Context, name string, linkUserID *uint) (string, string, error) {
+	client, err := GetOIDCClient(ctx, name)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	binding, err := RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	// Forget abandoned attempts
+	if err := database.DB.Where("expires_at < ?", time.Now()).Delete(&entity.OAuthState{}).Error; err != nil {
+		return "", "", err
+	}
+
+	if err := database.DB.Create(&entity.OAuthState{
+		State:        state,
+		Provider:     client.Name,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		BindingHash:  HashToken(binding),
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(OAuthStateTTL),
+	}).Error; err != nil {
+		return "", "", err
+	}
+
+	return client.Config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), binding, nil
+}
+
+// FinishOIDCFlow uses up the state, exchanges the code with its PKCE verifier and verifies the ID token and nonce.
+// The binding must be the one StartOIDCFlow returned, so a callback only completes in the browser that started it.
+func FinishOIDCFlow(ctx context.Context, name, code, state, binding string) (*OIDCClaims, *entity.OAuthState, error) {
+	client, err := GetOIDCClient(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if binding == "" {
+		return nil, nil, ErrInvalidOAuthState
+	}
+
+	// Use up state, so a callback cannot be replayed
+	var oauthState entity.OAuthState
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state = ? AND provider = ? AND binding_hash = ? AND expires_at > ?", state, client.Name, HashToken(binding), time.Now()).First(&oauthState).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrInvalidOAuthState
+			}
+			return err
+		}
+
+		result := tx.Where("state = ?", state).Delete(&entity.OAuthState{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidOAuthState
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Exchange code
+	token, err := client.Config.Exchange(ctx, code, oauth2.VerifierOption(oauthState.CodeVerifier))
+	if err != nil {
+		return nil, nil, fmt.Errorf("exchanging code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, nil, errors.New("the identity provider did not return an ID token")
+	}
+
+	// Verify ID token
+	idToken, err := client.Verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("verifying ID token: %w", err)
+	}
+	if idToken.Nonce != oauthState.Nonce {
+		return nil, nil, errors.New("ID token nonce does not match")
+	}
+
+	var claims OIDCClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, nil, err
+	}
+
+	return &claims, &oauthState, nil
+}
+
+// LoginWithOIDC finds the user of an external account. Unknown accounts are linked to the user with the same
+// email when that user has verified it, or a new verified user without a password is created. A user who has not
+// verified their email could have been registered by someone else to take over the account, so they have to sign
+// in with their password and link the provider from their profile instead.
+func LoginWithOIDC(provider string, claims *OIDCClaims) (*entity.User, error) {
+	var user entity.User
+
+	// Known identity
+	var identity entity.UserIdentity
+	err := database.DB.Preload("User").Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error
+	if err == nil {
+		return &identity.User, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	// Only trust emails the provider has verified
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+	if IsReservedEmail(claims.Email) {
+		return nil, ErrReservedEmail
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("email = ?", claims.Email).First(&user).Error
+		if err == gorm.ErrRecordNotFound {
+			name := claims.Name
+			if name == "" {
+				name = strings.Split(claims.Email, "@")[0]
+			}
+			user = entity.User{Name: name, Email: claims.Email, IsVerified: true}
+			err = tx.Create(&user).Error
+		}
+		if err != nil {
+			return err
+		}
+		if !user.IsVerified {
+			return ErrUnverifiedAccount
+		}
+
+		return createUserIdentity(tx, user.ID, provider, claims)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// LinkOIDCIdentity links an external account to the user.
+func LinkOIDCIdentity(userID uint, provider string, claims *OIDCClaims) error {
+	var identity entity.UserIdentity
+	err := database.DB.Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error
+	if err == nil {
+		if identity.UserID != userID {
+			return ErrIdentityLinked
+		}
+		return nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	return createUserIdentity(database.DB, userID, provider, claims)
+}
+
+// UnlinkOIDCIdentity removes a linked provider, unless it is the user's only way to sign in.
+func UnlinkOIDCIdentity(user *entity.User, provider string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&entity.UserIdentity{}).Where("user_id = ?", user.ID).Count(&count).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("user_id = ? AND provider = ?", user.ID, provider).Delete(&entity.UserIdentity{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if user.Password == "" && count <= 1 {
+			return ErrLastLoginMethod
+		}
+		return nil
+	})
+}
+
+func createUserIdentity(tx *gorm.DB, userID uint, provider string, claims *OIDCClaims) error {
+	var count int64
+	if err := tx.Model(&entity.UserIdentity{}).Where("user_id = ? AND provider = ?", userID, provider).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrProviderAlreadyUsed
+	}
+
+	return tx.Create(&entity.UserIdentity{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}).Error
+}
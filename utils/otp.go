@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOtpInvalid  = errors.New("invalid or expired OTP code")
+	ErrOtpLocked   = errors.New("too many failed attempts, please try again later")
+	ErrOtpCooldown = errors.New("please wait before requesting another OTP code")
+	ErrOtpHashKey  = errors.New("OTP_HASH_KEY must be a base64 encoded 32 byte key, or set JWT_KEY_ENCRYPTION_KEY")
+)
+
+func OtpLength() int {
+	length := GetEnvInt("OTP_LENGTH", 6)
+	if length < 4 || length > 10 {
+		return 6
+	}
+	return length
+}
+
+func OtpTTL() time.Duration {
+	return GetEnvDuration("OTP_TTL", 10*time.Minute)
+}
+
+func OtpMaxAttempts() int {
+	attempts := GetEnvInt("OTP_MAX_ATTEMPTS", 5)
+	if attempts <= 0 {
+		return 5
+	}
+	return attempts
+}
+
+func OtpResendCooldown() time.Duration {
+	return GetEnvDuration("OTP_RESEND_COOLDOWN", time.Minute)
+}
+
+func OtpLockout() time.Duration {
+	return GetEnvDuration("OTP_LOCKOUT", 15*time.Minute)
+}
+
+// GenerateOTP returns a numeric code of the given length drawn from crypto/rand.
+func GenerateOTP(length int) (string, error) {
+	var otp strings.Builder
+	for i := 0; i < length; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		otp.WriteString(digit.String())
+	}
+	return otp.String(), nil
+}
+
+// otpHashKey returns the HMAC key OTP codes are hashed with: OTP_HASH_KEY, or a key derived from
+// JWT_KEY_ENCRYPTION_KEY when it is not set. Codes are short, so without a secret key a leaked hash
+// could be reversed by trying every code.
+func otpHashKey() ([]byte, error) {
+	if encoded := os.Getenv("OTP_HASH_KEY"); encoded != "" {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, ErrOtpHashKey
+		}
+		return key, nil
+	}
+
+	kek, err := base64.StdEncoding.DecodeString(os.Getenv("JWT_KEY_ENCRYPTION_KEY"))
+	if err != nil || len(kek) != 32 {
+		return nil, ErrOtpHashKey
+	}
+	mac := hmac.New(sha256.New, kek)
+	mac.Write([]byte("otp-hash"))
+	return mac.Sum(nil), nil
+}
+
+// hashOtp binds the code to its user and purpose before hashing, so equal codes never share a hash.
+func hashOtp(userID uint, otpType entity.OtpType, otp string) (string, error) {
+	key, err := otpHashKey()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%d:%s:%s", userID, otpType, strings.TrimSpace(otp))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// IssueOtp generates a new code of the given type for the user, replacing any previous one.
+// Only the hash of the code is stored; the plain code is returned so it can be sent to the user.
+func IssueOtp(userID uint, otpType entity.OtpType) (string, error) {
+	now := time.Now()
+
+	var otpCode entity.OtpCode
+	err := database.DB.Where("user_id = ? AND type = ?", userID, otpType).First(&otpCode).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return "", err
+	}
+
+	if err == nil {
+		// Keep a locked code until its lockout ends
+		if otpCode.LockedUntil != nil && now.Before(*otpCode.LockedUntil) {
+			return "", ErrOtpLocked
+		}
+
+		// Throttle resends
+		if now.Before(otpCode.SentAt.Add(OtpResendCooldown())) {
+			return "", ErrOtpCooldown
+		}
+	}
+
+	otp, err := GenerateOTP(OtpLength())
+	if err != nil {
+		return "", err
+	}
+
+	otpCode.UserID = userID
+	otpCode.Type = otpType
+	if otpCode.Otp, err = hashOtp(userID, otpType, otp); err != nil {
+		return "", err
+	}
+	otpCode.ExpiredAt = now.Add(OtpTTL())
+	otpCode.Attempts = 0
+	otpCode.LockedUntil = nil
+	otpCode.SentAt = now
+
+	if err := database.DB.Save(&otpCode).Error; err != nil {
+		return "", err
+	}
+
+	return otp, nil
+}
+
+// ConsumeOtp checks the code and, when it matches, deletes it and runs apply in the same transaction.
+// A code can therefore be used only once, even by concurrent requests.
+func ConsumeOtp(userID uint, otpType entity.OtpType, otp string, apply func(tx *gorm.DB) error) error {
+	hash, err := hashOtp(userID, otpType, otp)
+	if err != nil {
+		return err
+	}
+
+	if err := reserveOtpAttempt(userID, otpType); err != nil {
+		return err
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND type = ? AND otp = ?", userID, otpType, hash).
+			Delete(&entity.OtpCode{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOtpInvalid
+		}
+
+		return apply(tx)
+	})
+	if errors.Is(err, ErrOtpInvalid) {
+		return lockExhaustedOtp(userID, otpType)
+	}
+	return err
+}
+
+// reserveOtpAttempt counts an attempt against the user's code before it is checked, so concurrent guesses
+// can never exceed the attempt limit.
+func reserveOtpAttempt(userID uint, otpType entity.OtpType) error {
+	now := time.Now()
+	result := database.DB.Model(&entity.OtpCode{}).
+		Where("user_id = ? AND type = ? AND expired_at > ? AND attempts < ?", userID, otpType, now, OtpMaxAttempts()).
+		Where("locked_until IS NULL OR locked_until <= ?", now).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 1 {
+		return nil
+	}
+
+	// Tell a locked code apart from a missing or expired one
+	var count int64
+	err := database.DB.Model(&entity.OtpCode{}).
+		Where("user_id = ? AND type = ? AND expired_at > ?", userID, otpType, now).
+		Where("attempts >= ? OR locked_until > ?", OtpMaxAttempts(), now).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrOtpLocked
+	}
+	return ErrOtpInvalid
+}
+
+// lockExhaustedOtp locks the code once its last attempt has failed and reports the failed attempt.
+func lockExhaustedOtp(userID uint, otpType entity.OtpType) error {
+	result := database.DB.Model(&entity.OtpCode{}).
+		Where("user_id = ? AND type = ? AND attempts >= ? AND locked_until IS NULL", userID, otpType, OtpMaxAttempts()).
+		Update("locked_until", time.Now().Add(OtpLockout()))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return ErrOtpLocked
+	}
+	return ErrOtpInvalid
+}
+
+// OtpErrorStatus maps OTP errors to the HTTP status the API responds with.
+func OtpErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrOtpInvalid):
+		return fiber.StatusUnauthorized
+	case errors.Is(err, ErrOtpLocked), errors.Is(err, ErrOtpCooldown):
+		return fiber.StatusTooManyRequests
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
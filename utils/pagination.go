@@ -0,0 +1,273 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-news-api/models/request"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPerPage = 10
+	MaxPerPage     = 100
+)
+
+// ErrInvalidQuery is returned when a list query has an unknown sort or a malformed cursor.
+var ErrInvalidQuery = errors.New("invalid query")
+
+type SortKind int
+
+const (
+	SortInt SortKind = iota
+	SortString
+	SortTime
+)
+
+// SortField describes a column a list can be sorted by. Value reads the
+// column from a row so the next cursor can be built from the last row.
+// Order is the direction used when the request has none, for sorts such as
+// "newest" that imply one.
+type SortField[T any] struct {
+	Column string
+	Kind   SortKind
+	Value  func(item T) interface{}
+	Order  string
+}
+
+// Paginator pages through a gorm query using either page numbers or cursors.
+type Paginator[T any] struct {
+	IDColumn     string
+	ID           func(item T) uint
+	Sorts        map[string]SortField[T]
+	DefaultSort  string
+	DefaultOrder string
+	// Prepare adds preloads or selects after the total has been counted.
+	Prepare func(db *gorm.DB) *gorm.DB
+}
+
+type Pagination struct {
+	Page       int             `json:"page,omitempty"`
+	PerPage    int             `json:"per_page"`
+	Total      int64           `json:"total"`
+	TotalPages int             `json:"total_pages"`
+	Sort       string          `json:"sort,omitempty"`
+	Order      string          `json:"order,omitempty"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	Links      PaginationLinks `json:"links"`
+}
+
+type PaginationLinks struct {
+	Self  string `json:"self"`
+	First string `json:"first,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last,omitempty"`
+}
+
+type cursor struct {
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// Paginate runs the query and returns one page of rows. When the request has a
+// cursor the rows after it are returned, otherwise the requested page.
+func (p Paginator[T]) Paginate(ctx *fiber.Ctx, db *gorm.DB, req request.PageRequest) ([]T, *Pagination, error) {
+	sortName := req.Sort
+	if sortName == "" {
+		sortName = p.DefaultSort
+	}
+	sort, ok := p.Sorts[sortName]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidQuery, sortName)
+	}
+
+	order := req.Order
+	if order == "" {
+		order = sort.Order
+	}
+	if order == "" {
+		order = p.DefaultOrder
+	}
+	if order != "asc" && order != "desc" {
+		order = "desc"
+	}
+
+	perPage := req.PerPage
+	if perPage <= 0 {
+		perPage = DefaultPerPage
+	}
+	if perPage > MaxPerPage {
+		perPage = MaxPerPage
+	}
+
+	// Count every matching row
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	query := db.Session(&gorm.Session{})
+	if p.Prepare != nil {
+		query = p.Prepare(query)
+	}
+
+	pagination := &Pagination{
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: int(math.Ceil(float64(total) / float64(perPage))),
+		Sort:       sortName,
+		Order:      order,
+	}
+
+	if req.Cursor != "" {
+		value, id, err := decodeCursor(req.Cursor, sort.Kind)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		operator := "<"
+		if order == "asc" {
+			operator = ">"
+		}
+		query = query.Where(
+			fmt.Sprintf("(%s %s ?) OR (%s = ? AND %s %s ?)", sort.Column, operator, sort.Column, p.IDColumn, operator),
+			value, value, id,
+		)
+	} else {
+		pagination.Page = req.Page
+		if pagination.Page <= 0 {
+			pagination.Page = 1
+		}
+		query = query.Offset((pagination.Page - 1) * perPage)
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	var items []T
+	if err := query.Order(fmt.Sprintf("%s %s, %s %s", sort.Column, order, p.IDColumn, order)).
+		Limit(perPage + 1).
+		Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+
+	hasNext := len(items) > perPage
+	if hasNext {
+		items = items[:perPage]
+		last := items[len(items)-1]
+		pagination.NextCursor = encodeCursor(sort.Value(last), sort.Kind, p.ID(last))
+	}
+
+	pagination.Links = paginationLinks(ctx, pagination, hasNext)
+
+	return items, pagination, nil
+}
+
+func encodeCursor(value interface{}, kind SortKind, id uint) string {
+	var raw string
+	switch kind {
+	case SortTime:
+		raw = value.(time.Time).UTC().Format(time.RFC3339Nano)
+	default:
+		raw = fmt.Sprint(value)
+	}
+
+	data, _ := json.Marshal(cursor{Value: raw, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(encoded string, kind SortKind) (interface{}, uint, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, 0, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	switch kind {
+	case SortTime:
+		value, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: cursor does not match sort", ErrInvalidQuery)
+		}
+		return value, c.ID, nil
+	case SortInt:
+		value, err := strconv.ParseInt(c.Value, 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: cursor does not match sort", ErrInvalidQuery)
+		}
+		return value, c.ID, nil
+	default:
+		return c.Value, c.ID, nil
+	}
+}
+
+// PagePagination describes a page of a list that was paged with an offset outside of a Paginator.
+func PagePagination(ctx *fiber.Ctx, page, perPage int, total int64) *Pagination {
+	pagination := &Pagination{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: int(math.Ceil(float64(total) / float64(perPage))),
+	}
+	pagination.Links = paginationLinks(ctx, pagination, int64(page*perPage) < total)
+	return pagination
+}
+
+// paginationLinks builds links to neighbouring pages from the current request URL.
+func paginationLinks(ctx *fiber.Ctx, pagination *Pagination, hasNext bool) PaginationLinks {
+	link := func(set map[string]string) string {
+		args := fiber.AcquireArgs()
+		defer fiber.ReleaseArgs(args)
+
+		ctx.Request().URI().QueryArgs().CopyTo(args)
+		for key, value := range set {
+			if value == "" {
+				args.Del(key)
+			} else {
+				args.Set(key, value)
+			}
+		}
+
+		url := ctx.BaseURL() + ctx.Path()
+		if query := args.String(); query != "" {
+			url += "?" + query
+		}
+		return url
+	}
+
+	links := PaginationLinks{Self: link(nil)}
+
+	// Cursor pages only know the way forward
+	if pagination.Page == 0 {
+		if hasNext {
+			links.Next = link(map[string]string{"cursor": pagination.NextCursor})
+		}
+		return links
+	}
+
+	page := func(number int) string {
+		return link(map[string]string{"page": strconv.Itoa(number), "cursor": ""})
+	}
+
+	links.First = page(1)
+	if pagination.TotalPages > 0 {
+		links.Last = page(pagination.TotalPages)
+	}
+	if pagination.Page > 1 {
+		links.Prev = page(pagination.Page - 1)
+	}
+	if hasNext {
+		links.Next = page(pagination.Page + 1)
+	}
+
+	return links
+}
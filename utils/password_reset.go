@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+func PasswordResetTokenTTL() time.Duration {
+	return GetEnvDuration("PASSWORD_RESET_TOKEN_TTL", 15*time.Minute)
+}
+
+// IssuePasswordResetToken exchanges a password reset OTP for a short-lived, single-use reset token.
+// The OTP is used up in the same transaction, and any earlier reset tokens of the user stop working.
+func IssuePasswordResetToken(userID uint, otp string) (string, error) {
+	token, err := RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = ConsumeOtp(userID, entity.PasswordReset, otp, func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&entity.PasswordResetToken{
+			UserID:    userID,
+			TokenHash: HashToken(token),
+			ExpiresAt: time.Now().Add(PasswordResetTokenTTL()),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ResetPasswordWithToken uses up the reset token, sets the new password hash and revokes every session of the user
+// in one transaction, so the token cannot be replayed and old access tokens stop working.
+func ResetPasswordWithToken(userID uint, token, passwordHash string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.PasswordResetToken{}).
+			Where("user_id = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", userID, HashToken(token), time.Now()).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidResetToken
+		}
+
+		if err := tx.Model(&entity.User{}).Where("id = ?", userID).Update("password", passwordHash).Error; err != nil {
+			return err
+		}
+
+		return RevokeUserSessions(tx, userID)
+	})
+}
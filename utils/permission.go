@@ -0,0 +1,86 @@
+package utils
+
+import "go-news-api/models/entity"
+
+type Permission string
+
+const (
+	ManageCategories Permission = "categories:manage"
+	ManageTags       Permission = "tags:manage"
+	ManageUsers      Permission = "users:manage"
+	WriteArticles    Permission = "articles:write"
+	EditAnyArticle   Permission = "articles:edit_any"
+	ReviewArticles   Permission = "articles:review"
+	WriteComments    Permission = "comments:write"
+	ModerateComments Permission = "comments:moderate"
+	ViewAuditLog     Permission = "audit:read"
+
+	// Read and profile scopes only matter for API keys; every signed in user can read their own data
+	// and edit their own profile.
+	ReadArticles Permission = "articles:read"
+	ReadComments Permission = "comments:read"
+	ReadProfile  Permission = "profile:read"
+	WriteProfile Permission = "profile:write"
+)
+
+// APIKeyScopes lists the scopes an API key can be given.
+var APIKeyScopes = []Permission{
+	ReadArticles, WriteArticles, EditAnyArticle, ReviewArticles,
+	ReadComments, WriteComments, ModerateComments,
+	ManageCategories, ManageTags, ManageUsers, ViewAuditLog,
+	ReadProfile, WriteProfile,
+}
+
+// RolePermissions lists what each role is allowed to do.
+var RolePermissions = map[entity.UserRole][]Permission{
+	entity.AdminRole: {
+		ManageCategories, ManageTags, ManageUsers, ViewAuditLog,
+		WriteArticles, EditAnyArticle, ReviewArticles,
+		WriteComments, ModerateComments,
+	},
+	entity.EditorRole: {
+		ManageCategories, ManageTags,
+		WriteArticles, EditAnyArticle, ReviewArticles,
+		WriteComments, ModerateComments,
+	},
+	entity.AuthorRole: {WriteArticles, WriteComments},
+	entity.ReaderRole: {WriteComments},
+}
+
+// HasPermission reports whether the user's role grants the permission and, for API key requests,
+// whether the key has it as a scope.
+func HasPermission(user *entity.User, permission Permission) bool {
+	if user == nil || !HasScope(user, permission) {
+		return false
+	}
+	for _, granted := range RolePermissions[user.Role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether a request may use the permission as far as its API key is concerned.
+// Session logins are not limited by scopes.
+func HasScope(user *entity.User, permission Permission) bool {
+	if user == nil || user.Scopes == nil {
+		return true
+	}
+	for _, scope := range user.Scopes {
+		if scope == string(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanManageArticle reports whether the user is the author of the article or may edit any article.
+func CanManageArticle(user *entity.User, article *entity.Article) bool {
+	return user != nil && (article.AuthorID == user.ID || HasPermission(user, EditAnyArticle))
+}
+
+// CanManageComment reports whether the user wrote the comment or may moderate any comment.
+func CanManageComment(user *entity.User, comment *entity.Comment) bool {
+	return user != nil && (comment.UserID == user.ID || HasPermission(user, ModerateComments))
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrWrongPassword  = errors.New("current password is incorrect")
+	ErrEmailTaken     = errors.New("email is already in use")
+	ErrNoPendingEmail = errors.New("there is no email change to confirm")
+	ErrSameEmail      = errors.New("new email is the same as the current email")
+	ErrPasswordNotSet = errors.New("this account has no password yet, set one with the reset password flow")
+)
+
+// EmailTaken reports whether another user already uses the email.
+func EmailTaken(tx *gorm.DB, email string, exceptUserID uint) (bool, error) {
+	var count int64
+	if err := tx.Model(&entity.User{}).Where("email = ? AND id <> ?", email, exceptUserID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ChangePassword sets the new password hash and revokes every other session of the user, keeping the one
+// the change was made from signed in.
+func ChangePassword(userID uint, currentSessionID, passwordHash string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.User{}).Where("id = ?", userID).Update("password", passwordHash).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&entity.Session{}).
+			Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, currentSessionID).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+// RequestEmailChange remembers the new email as pending and issues the OTP that confirms it.
+// The plain code is returned so it can be sent to the new address.
+func RequestEmailChange(user *entity.User, newEmail string) (string, error) {
+	if newEmail == user.Email {
+		return "", ErrSameEmail
+	}
+	if IsReservedEmail(newEmail) {
+		return "", ErrReservedEmail
+	}
+
+	taken, err := EmailTaken(database.DB, newEmail, user.ID)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrEmailTaken
+	}
+
+	otp, err := IssueOtp(user.ID, entity.EmailChange)
+	if err != nil {
+		return "", err
+	}
+
+	if err := database.DB.Model(user).Update("pending_email", newEmail).Error; err != nil {
+		return "", err
+	}
+	return otp, nil
+}
+
+// ConfirmEmailChange uses up the OTP and switches the user to the pending email, which counts as verified.
+// Outstanding verification and password reset codes were sent to the old address, so they stop working.
+// It returns the old email so it can be told about the change.
+func ConfirmEmailChange(userID uint, otp string) (*entity.User, string, error) {
+	var user entity.User
+	var oldEmail string
+
+	err := ConsumeOtp(userID, entity.EmailChange, otp, func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.PendingEmail == "" {
+			return ErrNoPendingEmail
+		}
+
+		taken, err := EmailTaken(tx, user.PendingEmail, user.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrEmailTaken
+		}
+
+		oldEmail = user.Email
+		user.Email = user.PendingEmail
+		user.PendingEmail = ""
+		user.IsVerified = true
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"email":         user.Email,
+			"pending_email": "",
+			"is_verified":   true,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ? AND type IN ?", user.ID, []entity.OtpType{entity.EmailVerification, entity.PasswordReset}).
+			Delete(&entity.OtpCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&entity.PasswordResetToken{}).Error
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return &user, oldEmail, nil
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"fmt"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PublicArticles limits a query to articles that are published and whose publish time has passed.
+func PublicArticles(db *gorm.DB) *gorm.DB {
+	return db.Where("articles.status = ? AND (articles.publish_at IS NULL OR articles.publish_at <= ?)", entity.Published, time.Now())
+}
+
+// PublishDueArticles publishes every approved article whose publish time has passed
+// and returns how many articles were published.
+func PublishDueArticles() (int, error) {
+	var articles []entity.Article
+	if err := database.DB.Where("status = ? AND publish_at <= ?", entity.Approved, time.Now()).Find(&articles).Error; err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for i := range articles {
+		article := &articles[i]
+		if err := TransitionArticle(article, entity.Published); err != nil {
+			return published, err
+		}
+
+		// Only update if the article is still approved, so concurrent publishers don't publish it twice
+		result := database.DB.Model(&entity.Article{}).
+			Where("id = ? AND status = ?", article.ID, entity.Approved).
+			Updates(map[string]interface{}{
+				"status":       article.Status,
+				"publish_at":   article.PublishAt,
+				"published_at": article.PublishedAt,
+			})
+		if result.Error != nil {
+			return published, result.Error
+		}
+		if result.RowsAffected > 0 {
+			published++
+			SyncSearchIndex(article.ID)
+		}
+	}
+
+	return published, nil
+}
+
+// StartPublisher runs PublishDueArticles in the background every interval.
+func StartPublisher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			count, err := PublishDueArticles()
+			if err != nil {
+				fmt.Printf("Error publishing due articles: %v\n", err)
+				continue
+			}
+			if count > 0 {
+				fmt.Printf("Published %d scheduled article(s).\n", count)
+			}
+		}
+	}()
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit is a token bucket budget: Limit requests, refilled evenly over Period.
+type RateLimit struct {
+	Limit  int
+	Period time.Duration
+}
+
+// RateLimitResult describes the bucket after taking a token.
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, when not allowed
+}
+
+// RateLimitStore keeps token buckets. The memory store suits a single node,
+// the database store shares the buckets between several nodes.
+type RateLimitStore interface {
+	// Take removes one token from the bucket under key if there is one, atomically.
+	Take(key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+// RateLimits is the store used by the rate limit middleware. It is replaced by SetupRateLimit.
+var RateLimits RateLimitStore = NewMemoryRateLimitStore()
+
+// SetupRateLimit selects the rate limit store by driver name, "memory" or "database".
+func SetupRateLimit(driver string) error {
+	switch driver {
+	case "", "memory":
+		RateLimits = NewMemoryRateLimitStore()
+	case "database":
+		RateLimits = &DatabaseRateLimitStore{}
+	default:
+		return fmt.Errorf("unknown rate limit store %q", driver)
+	}
+	return nil
+}
+
+// RateLimitPolicy returns the budget of a named policy. It can be overridden with
+// RATE_LIMIT_<NAME>, written as "<limit>/<period>", for example RATE_LIMIT_COMMENTS=10/1m.
+func RateLimitPolicy(name string, limit int, period time.Duration) RateLimit {
+	fallback := RateLimit{Limit: limit, Period: period}
+
+	value := os.Getenv("RATE_LIMIT_" + strings.ToUpper(name))
+	if value == "" {
+		return fallback
+	}
+
+	rateLimit, err := ParseRateLimit(value)
+	if err != nil {
+		fmt.Printf("Error parsing rate limit %s: %v\n", name, err)
+		return fallback
+	}
+	return rateLimit
+}
+
+// ParseRateLimit parses a budget written as "<limit>/<period>", like "5/15m".
+func ParseRateLimit(value string) (RateLimit, error) {
+	limitText, periodText, ok := strings.Cut(value, "/")
+	if !ok {
+		return RateLimit{}, errors.New("rate limit must look like <limit>/<period>")
+	}
+
+	limit, err := strconv.Atoi(strings.TrimSpace(limitText))
+	if err != nil || limit <= 0 {
+		return RateLimit{}, errors.New("rate limit must be a positive number")
+	}
+
+	period, err := time.ParseDuration(strings.TrimSpace(periodText))
+	if err != nil || period <= 0 {
+		return RateLimit{}, errors.New("rate limit period must be a positive duration")
+	}
+
+	return RateLimit{Limit: limit, Period: period}, nil
+}
+
+// takeToken refills a bucket for the time elapsed since it was last refilled, then takes one token from it.
+// It returns the tokens left in the bucket.
+func takeToken(tokens float64, refilledAt time.Time, limit RateLimit, now time.Time) (float64, RateLimitResult) {
+	capacity := float64(limit.Limit)
+	perSecond := capacity / limit.Period.Seconds()
+
+	if elapsed := now.Sub(refilledAt).Seconds(); elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed*perSecond)
+	}
+
+	result := RateLimitResult{}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - tokens) / perSecond)
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = secondsToDuration((capacity - tokens) / perSecond)
+
+	return tokens, result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"fmt"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DatabaseRateLimitStore keeps token buckets in the rate_limit_buckets table, shared by every node.
+type DatabaseRateLimitStore struct {
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+func (s *DatabaseRateLimitStore) Take(key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	s.prune(now)
+
+	var result RateLimitResult
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Make sure the bucket exists, then lock it for the read-modify-write
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&entity.RateLimitBucket{Key: key, Tokens: float64(limit.Limit), RefilledAt: now, FullAt: now}).Error; err != nil {
+			return err
+		}
+
+		var bucket entity.RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("bucket_key = ?", key).First(&bucket).Error; err != nil {
+			return err
+		}
+
+		bucket.Tokens, result = takeToken(bucket.Tokens, bucket.RefilledAt, limit, now)
+		bucket.RefilledAt = now
+		bucket.FullAt = now.Add(limit.Period)
+		return tx.Save(&bucket).Error
+	})
+	return result, err
+}
+
+// prune deletes buckets that have had time to fill up again, at most once a minute per node.
+// A full bucket behaves exactly like a missing one.
+func (s *DatabaseRateLimitStore) prune(now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastPrune) < time.Minute {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPrune = now
+	s.mu.Unlock()
+
+	// Buckets stored before full_at existed are kept for a day
+	if err := database.DB.Where("full_at < ? OR (full_at IS NULL AND refilled_at < ?)", now, now.Add(-24*time.Hour)).
+		Delete(&entity.RateLimitBucket{}).Error; err != nil {
+		fmt.Printf("Error pruning rate limit buckets: %v\n", err)
+	}
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryBucket struct {
+	tokens     float64
+	refilledAt time.Time
+	period     time.Duration
+}
+
+// MemoryRateLimitStore keeps token buckets in process memory.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryBucket
+	lastPrune time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]memoryBucket{}}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = memoryBucket{tokens: float64(limit.Limit), refilledAt: now}
+	}
+
+	tokens, result := takeToken(bucket.tokens, bucket.refilledAt, limit, now)
+	s.buckets[key] = memoryBucket{tokens: tokens, refilledAt: now, period: limit.Period}
+
+	return result, nil
+}
+
+// prune drops buckets that have had time to fill up again, at most once a minute.
+// A full bucket behaves exactly like a missing one.
+func (s *MemoryRateLimitStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < time.Minute {
+		return
+	}
+	s.lastPrune = now
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.refilledAt) > bucket.period {
+			delete(s.buckets, key)
+		}
+	}
+}
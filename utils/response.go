@@ -24,6 +24,17 @@ func SendErrorResponse(ctx *fiber.Ctx, status int, message string, err error) er
 	return ctx.Status(status).JSON(response)
 }
 
+// SendErrorResponseWithCode adds a machine-readable code to the error response, for errors
+// the client is expected to act on.
+func SendErrorResponseWithCode(ctx *fiber.Ctx, status int, code string, message string, err error) error {
+	return ctx.Status(status).JSON(fiber.Map{
+		"success": false,
+		"message": message,
+		"code":    code,
+		"errors":  []string{err.Error()},
+	})
+}
+
 func SendSuccessResponse(ctx *fiber.Ctx, status int, message string) error {
 	return ctx.Status(status).JSON(fiber.Map{
 		"success": true,
@@ -38,3 +49,12 @@ func SendSuccessResponseWithData(ctx *fiber.Ctx, status int, message string, dat
 		"data":    data,
 	})
 }
+
+func SendPaginatedResponse(ctx *fiber.Ctx, status int, message string, data interface{}, pagination *Pagination) error {
+	return ctx.Status(status).JSON(fiber.Map{
+		"success":    true,
+		"message":    message,
+		"data":       data,
+		"pagination": pagination,
+	})
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"go-news-api/database"
+	"go-news-api/models/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveArticle saves the article together with its slug history, tags and next revision in one transaction.
+// oldSlug is the slug the article had before, or its current slug when it is new.
+func SaveArticle(article *entity.Article, oldSlug string, tags []entity.Tag, editorID uint) (*entity.ArticleRevision, error) {
+	var revision *entity.ArticleRevision
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(article).Error; err != nil {
+			return err
+		}
+
+		// Keep the old slug so links to it are redirected
+		if err := RecordSlugChange(tx, article.ID, oldSlug, article.Slug); err != nil {
+			return err
+		}
+
+		if err := AssociateTagsWithArticle(tx, article.ID, tags); err != nil {
+			return err
+		}
+
+		var err error
+		revision, err = CreateArticleRevision(tx, article, tags, editorID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// CreateArticleRevision stores a snapshot of the article as its next revision. The article row stays locked
+// until tx ends, so concurrent saves of the article get consecutive versions instead of colliding.
+func CreateArticleRevision(tx *gorm.DB, article *entity.Article, tags []entity.Tag, editorID uint) (*entity.ArticleRevision, error) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&entity.Article{}, article.ID).Error; err != nil {
+		return nil, err
+	}
+
+	var latest uint
+	if err := tx.Model(&entity.ArticleRevision{}).
+		Where("article_id = ?", article.ID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&latest).Error; err != nil {
+		return nil, err
+	}
+
+	tagNames := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tagNames = append(tagNames, tag.Name)
+	}
+
+	revision := entity.ArticleRevision{
+		ArticleID:  article.ID,
+		Version:    latest + 1,
+		Title:      article.Title,
+		Slug:       article.Slug,
+		Thumbnail:  article.Thumbnail,
+		Content:    article.Content,
+		CategoryID: article.CategoryID,
+		Tags:       tagNames,
+		EditorID:   editorID,
+	}
+
+	if err := tx.Create(&revision).Error; err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"html"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// SearchIndex finds published articles matching a search query, ranked by relevance.
+type SearchIndex interface {
+	Search(query SearchQuery) ([]SearchHit, int64, error)
+}
+
+// SearchIndexer is implemented by indexes that keep their own copy of the articles
+// and need to be told when an article changes. Database-backed indexes don't.
+type SearchIndexer interface {
+	Index(article entity.Article) error
+	Remove(articleID uint) error
+}
+
+// Search is the index used by the search endpoint. It is replaced by SetupSearch.
+var Search SearchIndex = MySQLSearchIndex{}
+
+type SearchQuery struct {
+	Terms      []string
+	Phrases    []string
+	Excluded   []string
+	CategoryID uint
+	Tag        string
+	Offset     int
+	Limit      int
+}
+
+type SearchHit struct {
+	ArticleID uint    `json:"article_id"`
+	Score     float64 `json:"score"`
+}
+
+// SetupSearch selects the search index by driver name, "mysql" or "memory".
+// The in-process memory index is filled with the current articles.
+func SetupSearch(driver string) error {
+	switch driver {
+	case "", "mysql":
+		Search = MySQLSearchIndex{}
+	case "memory":
+		index := NewMemorySearchIndex()
+
+		var articles []entity.Article
+		if err := database.DB.Preload("Tags").Find(&articles).Error; err != nil {
+			return err
+		}
+		for _, article := range articles {
+			if err := index.Index(article); err != nil {
+				return err
+			}
+		}
+
+		Search = index
+	default:
+		return fmt.Errorf("unknown search driver %q", driver)
+	}
+
+	return nil
+}
+
+// SyncSearchIndex updates the search index after an article was saved or deleted.
+// Errors are logged rather than returned so they never fail the write itself.
+func SyncSearchIndex(articleID uint) {
+	indexer, ok := Search.(SearchIndexer)
+	if !ok {
+		return
+	}
+
+	var article entity.Article
+	err := database.DB.Preload("Tags").First(&article, articleID).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		err = indexer.Remove(articleID)
+	case err == nil:
+		err = indexer.Index(article)
+	}
+
+	if err != nil {
+		fmt.Printf("Error updating search index for article %d: %v\n", articleID, err)
+	}
+}
+
+// SyncSearchIndexes updates the search index after a change to many articles at once, such as a renamed tag.
+func SyncSearchIndexes(articleIDs []uint) {
+	for _, articleID := range articleIDs {
+		SyncSearchIndex(articleID)
+	}
+}
+
+// ParseSearchQuery splits a query into terms, "quoted phrases" and -excluded terms or phrases.
+func ParseSearchQuery(q string) SearchQuery {
+	var query SearchQuery
+
+	runes := []rune(q)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		excluded := false
+		if runes[i] == '-' {
+			excluded = true
+			i++
+		}
+
+		var text string
+		if i < len(runes) && runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			text = string(runes[i+1 : min(end, len(runes))])
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) {
+				end++
+			}
+			text = string(runes[i:end])
+			i = end
+		}
+
+		words := SearchTokens(text)
+		switch {
+		case len(words) == 0:
+		case excluded:
+			query.Excluded = append(query.Excluded, strings.Join(words, " "))
+		case len(words) == 1:
+			query.Terms = append(query.Terms, words[0])
+		default:
+			query.Phrases = append(query.Phrases, strings.Join(words, " "))
+		}
+	}
+
+	return query
+}
+
+// SearchTokens lowercases text and splits it into words of letters and digits.
+func SearchTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// HighlightSearch returns an HTML-escaped excerpt of text of about size characters
+// around the first match of the query, with every match wrapped in <mark>.
+func HighlightSearch(text string, query SearchQuery, size int) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		lower = runes
+	}
+
+	var needles [][]rune
+	for _, phrase := range query.Phrases {
+		needles = append(needles, []rune(phrase))
+	}
+	for _, term := range query.Terms {
+		needles = append(needles, []rune(term))
+	}
+
+	// Find every whole-word match
+	type match struct{ start, end int }
+	var matches []match
+	for i := 0; i < len(lower); {
+		found := false
+		if i == 0 || !isWordRune(lower[i-1]) {
+			for _, needle := range needles {
+				end := i + len(needle)
+				if end <= len(lower) && string(lower[i:end]) == string(needle) && (end == len(lower) || !isWordRune(lower[end])) {
+					matches = append(matches, match{i, end})
+					i = end
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			i++
+		}
+	}
+
+	// Cut a window around the first match
+	start, end := 0, len(runes)
+	if size > 0 && len(runes) > size {
+		if len(matches) > 0 {
+			start = max(matches[0].start-size/4, 0)
+		}
+		end = min(start+size, len(runes))
+	}
+
+	var snippet strings.Builder
+	if start > 0 {
+		snippet.WriteString("…")
+	}
+	position := start
+	for _, m := range matches {
+		if m.end <= start || m.start >= end {
+			continue
+		}
+		from, to := max(m.start, start), min(m.end, end)
+		snippet.WriteString(html.EscapeString(string(runes[position:from])))
+		snippet.WriteString("<mark>" + html.EscapeString(string(runes[from:to])) + "</mark>")
+		position = to
+	}
+	snippet.WriteString(html.EscapeString(string(runes[position:end])))
+	if end < len(runes) {
+		snippet.WriteString("…")
+	}
+
+	return snippet.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"fmt"
+	"go-news-api/models/entity"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemorySearchIndex is an in-process search index. It ranks like MySQLSearchIndex
+// and is meant for tests and single-node setups without a FULLTEXT index.
+type MemorySearchIndex struct {
+	mu        sync.RWMutex
+	documents map[uint]searchDocument
+}
+
+type searchDocument struct {
+	article entity.Article
+	title   []string
+	content []string
+	tags    map[string]bool
+}
+
+func NewMemorySearchIndex() *MemorySearchIndex {
+	return &MemorySearchIndex{documents: make(map[uint]searchDocument)}
+}
+
+func (index *MemorySearchIndex) Index(article entity.Article) error {
+	document := searchDocument{
+		article: article,
+		title:   SearchTokens(article.Title),
+		content: SearchTokens(article.Content),
+		tags:    make(map[string]bool),
+	}
+	for _, tag := range article.Tags {
+		document.tags[strings.ToLower(tag.Name)] = true
+	}
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.documents[article.ID] = document
+
+	return nil
+}
+
+func (index *MemorySearchIndex) Remove(articleID uint) error {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	delete(index.documents, articleID)
+
+	return nil
+}
+
+func (index *MemorySearchIndex) Search(query SearchQuery) ([]SearchHit, int64, error) {
+	if len(query.Terms) == 0 && len(query.Phrases) == 0 {
+		return nil, 0, fmt.Errorf("%w: search query must contain at least one term", ErrInvalidQuery)
+	}
+
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	// Count documents containing each term for inverse document frequency
+	frequency := make(map[string]int)
+	for _, document := range index.documents {
+		for _, term := range query.Terms {
+			if countTerm(document.title, term)+countTerm(document.content, term) > 0 {
+				frequency[term]++
+			}
+		}
+	}
+
+	now := time.Now()
+	var hits []SearchHit
+	for id, document := range index.documents {
+		article := document.article
+		if article.Status != entity.Published || (article.PublishAt != nil && article.PublishAt.After(now)) {
+			continue
+		}
+		if query.CategoryID != 0 && article.CategoryID != query.CategoryID {
+			continue
+		}
+		if query.Tag != "" && !document.tags[strings.ToLower(query.Tag)] {
+			continue
+		}
+		if document.excluded(query.Excluded) {
+			continue
+		}
+
+		// Text matches need every term and phrase, tag matches need any term
+		score, textMatch := 0.0, true
+		for _, term := range query.Terms {
+			tf := 3*countTerm(document.title, term) + countTerm(document.content, term)
+			if tf == 0 {
+				textMatch = false
+			}
+			score += float64(tf) * math.Log(1+float64(len(index.documents))/float64(max(frequency[term], 1)))
+		}
+		for _, phrase := range query.Phrases {
+			words := strings.Fields(phrase)
+			occurrences := 3*countPhrase(document.title, words) + countPhrase(document.content, words)
+			if occurrences == 0 {
+				textMatch = false
+			}
+			score += 2 * float64(occurrences)
+		}
+		if !textMatch {
+			score = 0
+		}
+
+		tagMatch := false
+		for _, term := range query.Terms {
+			if document.tags[term] {
+				tagMatch = true
+			}
+		}
+		if tagMatch {
+			score++
+		}
+
+		if textMatch || tagMatch {
+			hits = append(hits, SearchHit{ArticleID: id, Score: score})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ArticleID > hits[j].ArticleID
+	})
+
+	total := int64(len(hits))
+	start := min(query.Offset, len(hits))
+	end := len(hits)
+	if query.Limit > 0 {
+		end = min(start+query.Limit, len(hits))
+	}
+
+	return hits[start:end], total, nil
+}
+
+func (document searchDocument) excluded(excluded []string) bool {
+	for _, text := range excluded {
+		words := strings.Fields(text)
+		if countPhrase(document.title, words)+countPhrase(document.content, words) > 0 {
+			return true
+		}
+		if len(words) == 1 && document.tags[words[0]] {
+			return true
+		}
+	}
+	return false
+}
+
+func countTerm(tokens []string, term string) int {
+	return countPhrase(tokens, []string{term})
+}
+
+func countPhrase(tokens []string, words []string) int {
+	occurrences := 0
+	for i := 0; i+len(words) <= len(tokens); i++ {
+		matched := true
+		for j, word := range words {
+			if tokens[i+j] != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			occurrences++
+		}
+	}
+	return occurrences
+}
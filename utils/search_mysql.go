@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"fmt"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const articleFullTextMatch = "MATCH(articles.title, articles.content) AGAINST(? IN BOOLEAN MODE)"
+
+// MySQLSearchIndex searches with the FULLTEXT index on article titles and contents.
+// Articles tagged with one of the query terms also match and rank higher.
+type MySQLSearchIndex struct{}
+
+func (MySQLSearchIndex) Search(query SearchQuery) ([]SearchHit, int64, error) {
+	if len(query.Terms) == 0 && len(query.Phrases) == 0 {
+		return nil, 0, fmt.Errorf("%w: search query must contain at least one term", ErrInvalidQuery)
+	}
+
+	// Every term and phrase is required in boolean mode
+	var required []string
+	for _, term := range query.Terms {
+		required = append(required, "+"+term)
+	}
+	for _, phrase := range query.Phrases {
+		required = append(required, `+"`+phrase+`"`)
+	}
+	against := strings.Join(required, " ")
+
+	db := database.DB.Model(&entity.Article{}).Scopes(PublicArticles)
+
+	if len(query.Terms) > 0 {
+		db = db.Where(articleFullTextMatch+" OR articles.id IN (?)", against, taggedArticleIDs(query.Terms))
+	} else {
+		db = db.Where(articleFullTextMatch, against)
+	}
+
+	if len(query.Excluded) > 0 {
+		var excluded []string
+		var excludedTags []string
+		for _, text := range query.Excluded {
+			excluded = append(excluded, `"`+text+`"`)
+			if !strings.Contains(text, " ") {
+				excludedTags = append(excludedTags, text)
+			}
+		}
+		db = db.Where("NOT "+articleFullTextMatch, strings.Join(excluded, " "))
+		if len(excludedTags) > 0 {
+			db = db.Where("articles.id NOT IN (?)", taggedArticleIDs(excludedTags))
+		}
+	}
+
+	if query.CategoryID != 0 {
+		db = db.Where("articles.category_id = ?", query.CategoryID)
+	}
+	if query.Tag != "" {
+		db = db.Where("articles.id IN (?)", taggedArticleIDs([]string{query.Tag}))
+	}
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var hits []SearchHit
+	score := articleFullTextMatch
+	args := []interface{}{against}
+	if len(query.Terms) > 0 {
+		score += " + CASE WHEN articles.id IN (?) THEN 1 ELSE 0 END"
+		args = append(args, taggedArticleIDs(query.Terms))
+	}
+	if err := db.Select("articles.id AS article_id, ("+score+") AS score", args...).
+		Order("score DESC, articles.id DESC").
+		Offset(query.Offset).
+		Limit(query.Limit).
+		Scan(&hits).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return hits, total, nil
+}
+
+// taggedArticleIDs selects the IDs of articles that have one of the given tags.
+func taggedArticleIDs(tagNames []string) *gorm.DB {
+	lowered := make([]string, 0, len(tagNames))
+	for _, name := range tagNames {
+		lowered = append(lowered, strings.ToLower(name))
+	}
+
+	return database.DB.Table("article_tags").
+		Select("article_tags.article_id").
+		Joins("JOIN tags ON tags.id = article_tags.tag_id").
+		Where("LOWER(tags.name) IN ?", lowered)
+}
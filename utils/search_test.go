@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"errors"
+	"go-news-api/models/entity"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  SearchQuery
+	}{
+		{name: "empty", query: "  ", want: SearchQuery{}},
+		{name: "terms", query: "Go  Fiber", want: SearchQuery{Terms: []string{"go", "fiber"}}},
+		{name: "required terms", query: "+go +fiber", want: SearchQuery{Terms: []string{"go", "fiber"}}},
+		{name: "phrase", query: `"Hello, World" api`, want: SearchQuery{Terms: []string{"api"}, Phrases: []string{"hello world"}}},
+		{name: "quoted single word", query: `"go"`, want: SearchQuery{Terms: []string{"go"}}},
+		{name: "unterminated phrase", query: `"hello world`, want: SearchQuery{Phrases: []string{"hello world"}}},
+		{name: "hyphenated word", query: "real-time", want: SearchQuery{Phrases: []string{"real time"}}},
+		{name: "excluded term", query: "go -java", want: SearchQuery{Terms: []string{"go"}, Excluded: []string{"java"}}},
+		{name: "excluded phrase", query: `go -"Spring Boot"`, want: SearchQuery{Terms: []string{"go"}, Excluded: []string{"spring boot"}}},
+		{name: "lone minus", query: "- go", want: SearchQuery{Terms: []string{"go"}}},
+		{name: "punctuation only", query: `-- ++ ""`, want: SearchQuery{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ParseSearchQuery(test.query); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseSearchQuery(%q) = %+v, want %+v", test.query, got, test.want)
+			}
+		})
+	}
+}
+
+func TestHighlightSearch(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		size  int
+		want  string
+	}{
+		{name: "every match", text: "Go is fun, go!", query: "go", want: "<mark>Go</mark> is fun, <mark>go</mark>!"},
+		{name: "whole words only", text: "gopher go ago", query: "go", want: "gopher <mark>go</mark> ago"},
+		{name: "phrase", text: "I like hello world a lot", query: `"hello world"`, want: "I like <mark>hello world</mark> a lot"},
+		{name: "phrase before its words", text: "hello world, hello", query: `"hello world" hello`, want: "<mark>hello world</mark>, <mark>hello</mark>"},
+		{name: "html is escaped", text: "<b>go</b> & more", query: "go", want: "&lt;b&gt;<mark>go</mark>&lt;/b&gt; &amp; more"},
+		{name: "window around first match", text: "aaaa bbbb cccc dddd go eeee ffff", query: "go", size: 12, want: "…dd <mark>go</mark> eeee f…"},
+		{name: "window without match", text: "short text", query: "missing", size: 5, want: "short…"},
+		{name: "short text is not cut", text: "go", query: "go", size: 10, want: "<mark>go</mark>"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := HighlightSearch(test.text, ParseSearchQuery(test.query), test.size)
+			if got != test.want {
+				t.Errorf("HighlightSearch(%q, %q, %d) = %q, want %q", test.text, test.query, test.size, got, test.want)
+			}
+		})
+	}
+}
+
+func TestMemorySearchIndex(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	index := newTestSearchIndex(t, []entity.Article{
+		{ID: 1, Title: "Go concurrency", Content: "Goroutines and channels in go", Status: entity.Published, CategoryID: 1, Tags: []entity.Tag{{Name: "Go"}}},
+		{ID: 2, Title: "Rust ownership", Content: "Borrowing explained, go is mentioned once", Status: entity.Published, CategoryID: 2},
+		{ID: 3, Title: "Go draft", Content: "go go go", Status: entity.Draft, CategoryID: 1},
+		{ID: 4, Title: "Go in the future", Content: "go", Status: entity.Published, PublishAt: &future, CategoryID: 1},
+		{ID: 5, Title: "Cooking pasta", Content: "Boil water", Status: entity.Published, CategoryID: 2, Tags: []entity.Tag{{Name: "go"}}},
+		{ID: 6, Title: "Hello world in Go", Content: "Print hello world", Status: entity.Published, CategoryID: 1},
+	})
+
+	tests := []struct {
+		name      string
+		query     string
+		category  uint
+		tag       string
+		offset    int
+		limit     int
+		wantIDs   []uint
+		wantTotal int64
+	}{
+		{name: "ranks title matches above content and tag matches", query: "go", wantIDs: []uint{1, 6, 5, 2}, wantTotal: 4},
+		{name: "requires every term in the text", query: "go channels", wantIDs: []uint{1, 5}, wantTotal: 2},
+		{name: "phrase", query: `"hello world"`, wantIDs: []uint{6}, wantTotal: 1},
+		{name: "phrase must be adjacent", query: `"world hello"`, wantIDs: nil, wantTotal: 0},
+		{name: "excluded term", query: "go -rust", wantIDs: []uint{1, 6, 5}, wantTotal: 3},
+		{name: "excluded tag", query: "hello -go", wantIDs: nil, wantTotal: 0},
+		{name: "excluded phrase", query: `go -"cooking pasta"`, wantIDs: []uint{1, 6, 2}, wantTotal: 3},
+		{name: "category filter", query: "go", category: 2, wantIDs: []uint{5, 2}, wantTotal: 2},
+		{name: "tag filter", query: "go", tag: "GO", wantIDs: []uint{1, 5}, wantTotal: 2},
+		{name: "page", query: "go", offset: 1, limit: 2, wantIDs: []uint{6, 5}, wantTotal: 4},
+		{name: "page past the end", query: "go", offset: 10, limit: 2, wantIDs: nil, wantTotal: 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query := ParseSearchQuery(test.query)
+			query.CategoryID, query.Tag, query.Offset, query.Limit = test.category, test.tag, test.offset, test.limit
+
+			hits, total, err := index.Search(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := hitIDs(hits); !reflect.DeepEqual(ids, test.wantIDs) || total != test.wantTotal {
+				t.Errorf("Search(%q) = %v of %d, want %v of %d", test.query, ids, total, test.wantIDs, test.wantTotal)
+			}
+		})
+	}
+
+	t.Run("rejects a query without terms", func(t *testing.T) {
+		if _, _, err := index.Search(ParseSearchQuery("-go")); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("expected ErrInvalidQuery, got %v", err)
+		}
+	})
+
+	t.Run("reindexes and removes articles", func(t *testing.T) {
+		if err := index.Index(entity.Article{ID: 2, Title: "Rust ownership", Content: "Borrowing explained", Status: entity.Published, CategoryID: 2}); err != nil {
+			t.Fatal(err)
+		}
+		if err := index.Remove(1); err != nil {
+			t.Fatal(err)
+		}
+
+		hits, _, err := index.Search(ParseSearchQuery("go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := hitIDs(hits); !reflect.DeepEqual(ids, []uint{6, 5}) {
+			t.Errorf("Search(\"go\") = %v, want [6 5]", ids)
+		}
+	})
+}
+
+func newTestSearchIndex(t *testing.T, articles []entity.Article) *MemorySearchIndex {
+	index := NewMemorySearchIndex()
+	for _, article := range articles {
+		if err := index.Index(article); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return index
+}
+
+func hitIDs(hits []SearchHit) []uint {
+	var ids []uint
+	for _, hit := range hits {
+		ids = append(ids, hit.ArticleID)
+	}
+	return ids
+}
@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used, all tokens of this session have been revoked")
+)
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+func AccessTokenTTL() time.Duration {
+	return GetEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute)
+}
+
+func RefreshTokenTTL() time.Duration {
+	return GetEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
+
+// SessionMaxAge is how long a session lasts at most from the login, however often it is refreshed.
+func SessionMaxAge() time.Duration {
+	return GetEnvDuration("SESSION_MAX_AGE", 90*24*time.Hour)
+}
+
+// HashToken returns the SHA-256 hex digest under which an opaque token is stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RandomToken returns a URL-safe random string made of size random bytes.
+func RandomToken(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// lastSeenInterval limits how often a session's last seen time is written.
+const lastSeenInterval = time.Minute
+
+// CreateSession starts a new session for the user from the given device and issues its first token pair.
+func CreateSession(user *entity.User, userAgent, ipAddress string) (*entity.Session, *TokenPair, error) {
+	if user.IsSystem {
+		return nil, nil, ErrSystemAccount
+	}
+
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	now := time.Now()
+	session := entity.Session{
+		ID:         uuid.NewString(),
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(RefreshTokenTTL()),
+	}
+	if err := database.DB.Create(&session).Error; err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := issueTokens(database.DB, &session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &session, tokens, nil
+}
+
+// RefreshSession exchanges a refresh token for a new token pair. A refresh token
+// can only be used once, presenting it again revokes its whole session. Sessions older
+// than SessionMaxAge cannot be refreshed, the user has to log in again.
+func RefreshSession(refreshToken string) (*entity.Session, *TokenPair, error) {
+	var session entity.Session
+	var tokens *TokenPair
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var token entity.RefreshToken
+		if err := tx.Preload("Session").First(&token, "token_hash = ?", HashToken(refreshToken)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		session = token.Session
+
+		now := time.Now()
+		if session.RevokedAt != nil || now.After(session.ExpiresAt) || now.After(token.ExpiresAt) ||
+			now.After(session.CreatedAt.Add(SessionMaxAge())) {
+			return ErrInvalidRefreshToken
+		}
+
+		// Mark the token as used, unless someone else got there first
+		result := tx.Model(&entity.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		var err error
+		tokens, err = issueTokens(tx, &session)
+		return err
+	})
+
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if revokeErr := RevokeSession(session.ID); revokeErr != nil {
+			return nil, nil, revokeErr
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &session, tokens, nil
+}
+
+// TouchSession records that the session was just used. It writes at most once per lastSeenInterval.
+func TouchSession(session *entity.Session, ipAddress string) error {
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) < lastSeenInterval && session.IPAddress == ipAddress {
+		return nil
+	}
+
+	session.LastSeenAt = now
+	session.IPAddress = ipAddress
+	return database.DB.Model(session).Updates(map[string]interface{}{
+		"last_seen_at": now,
+		"ip_address":   ipAddress,
+	}).Error
+}
+
+// RevokeSession revokes a session so neither its access nor refresh tokens are accepted any more.
+func RevokeSession(sessionID string) error {
+	return database.DB.Model(&entity.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserSessions revokes every session of the user, using tx so callers can revoke as part of a transaction.
+func RevokeUserSessions(tx *gorm.DB, userID uint) error {
+	return tx.Model(&entity.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// issueTokens signs an access token for the session and stores a new refresh token in it.
+func issueTokens(tx *gorm.DB, session *entity.Session) (*TokenPair, error) {
+	now := time.Now()
+	ttl := AccessTokenTTL()
+
+	// Every new refresh token extends the session, up to its maximum age
+	session.ExpiresAt = now.Add(RefreshTokenTTL())
+	if maxExpiresAt := session.CreatedAt.Add(SessionMaxAge()); session.ExpiresAt.After(maxExpiresAt) {
+		session.ExpiresAt = maxExpiresAt
+	}
+	session.LastSeenAt = now
+	if err := tx.Model(session).Updates(map[string]interface{}{
+		"expires_at":   session.ExpiresAt,
+		"last_seen_at": session.LastSeenAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	accessToken, err := GenerateToken(&jwt.MapClaims{
+		"user_id": session.UserID,
+		"sid":     session.ID,
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Create(&entity.RefreshToken{
+		TokenHash: HashToken(refreshToken),
+		SessionID: session.ID,
+		ExpiresAt: session.ExpiresAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(ttl.Seconds()),
+	}, nil
+}
@@ -0,0 +1,427 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	SigningAlgorithmRS256 = "RS256"
+	SigningAlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnknownSigningKey    = errors.New("unknown or retired signing key")
+	ErrNoSigningKey         = errors.New("no active signing key, run the rotate-keys command")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm, use RS256 or EdDSA")
+	ErrKeyEncryptionKey     = errors.New("JWT_KEY_ENCRYPTION_KEY must be a base64 encoded 32 byte key")
+)
+
+const (
+	// signingKeyRefreshInterval is how often the cached keys are reloaded, so keys rotated
+	// by another process are picked up.
+	signingKeyRefreshInterval = time.Minute
+
+	// signingKeyMissInterval limits the reloads caused by tokens with an unknown kid.
+	signingKeyMissInterval = 5 * time.Second
+
+	// JWKSMaxAge is how long services may cache the JWKS.
+	JWKSMaxAge = 5 * time.Minute
+
+	// KeyActivationDelay is how long a new key should only be published before it signs tokens, so every
+	// service caching the JWKS, and every process of this API, knows the key by then.
+	KeyActivationDelay = JWKSMaxAge + signingKeyRefreshInterval
+
+	// encryptedKeyPrefix marks a private key or secret stored encrypted with the key encryption key.
+	encryptedKeyPrefix = "enc:v1:"
+)
+
+type signingKey struct {
+	kid         string
+	method      jwt.SigningMethod
+	private     crypto.Signer
+	activatesAt time.Time
+	retiresAt   *time.Time
+}
+
+func (k *signingKey) activeAt(now time.Time) bool {
+	return !k.activatesAt.After(now) && !k.retiredAt(now)
+}
+
+func (k *signingKey) retiredAt(now time.Time) bool {
+	return k.retiresAt != nil && !k.retiresAt.After(now)
+}
+
+// signingKeys caches the keys that have not retired, oldest activation first.
+var signingKeys struct {
+	mu       sync.RWMutex
+	keys     []*signingKey
+	loadedAt time.Time
+}
+
+// SigningAlgorithm is the algorithm new keys are generated for, RS256 unless JWT_SIGNING_ALGORITHM says otherwise.
+func SigningAlgorithm() string {
+	if algorithm := os.Getenv("JWT_SIGNING_ALGORITHM"); algorithm != "" {
+		return algorithm
+	}
+	return SigningAlgorithmRS256
+}
+
+// EnsureSigningKey loads the signing keys and generates a first one when none is active.
+// Keys stored before private keys were encrypted are encrypted first.
+func EnsureSigningKey() error {
+	if err := encryptStoredSigningKeys(); err != nil {
+		return err
+	}
+	if err := loadSigningKeys(); err != nil {
+		return err
+	}
+	if _, err := currentSigningKey(); err == nil {
+		return nil
+	}
+
+	key, err := newSigningKey(SigningAlgorithm())
+	if err != nil {
+		return err
+	}
+	key.ActivatesAt = time.Now()
+	if err := database.DB.Create(key).Error; err != nil {
+		return err
+	}
+	return loadSigningKeys()
+}
+
+// RotateSigningKeys generates a new key that starts signing tokens after activateIn. The keys it
+// replaces keep verifying tokens for grace after that, so tokens they signed can run out.
+// Keys that have already retired are deleted.
+func RotateSigningKeys(algorithm string, activateIn, grace time.Duration) (*entity.SigningKey, error) {
+	key, err := newSigningKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	key.ActivatesAt = now.Add(activateIn)
+	retiresAt := key.ActivatesAt.Add(grace)
+
+	tx := database.DB.Begin()
+	if err := tx.Where("retires_at <= ?", now).Delete(&entity.SigningKey{}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Model(&entity.SigningKey{}).
+		Where("retires_at IS NULL OR retires_at > ?", retiresAt).
+		Update("retires_at", retiresAt).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Create(key).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	if err := loadSigningKeys(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetSigningKeys returns the stored keys, newest first.
+func GetSigningKeys() ([]entity.SigningKey, error) {
+	var keys []entity.SigningKey
+	if err := database.DB.Order("activates_at DESC").Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// JWKS returns the public keys of every key that has not retired, for services verifying our tokens.
+func JWKS() (*jose.JSONWebKeySet, error) {
+	keys, err := cachedSigningKeys(signingKeyRefreshInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	set := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	for _, key := range keys {
+		if key.retiredAt(now) {
+			continue
+		}
+		set.Keys = append(set.Keys, jose.JSONWebKey{
+			Key:       key.private.Public(),
+			KeyID:     key.kid,
+			Algorithm: key.method.Alg(),
+			Use:       "sig",
+		})
+	}
+	return set, nil
+}
+
+// currentSigningKey returns the newest activated key that has not retired.
+func currentSigningKey() (*signingKey, error) {
+	keys, err := cachedSigningKeys(signingKeyRefreshInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := len(keys) - 1; i >= 0; i-- {
+		if keys[i].activeAt(now) {
+			return keys[i], nil
+		}
+	}
+	return nil, ErrNoSigningKey
+}
+
+// lookupSigningKey returns the key with the kid as long as it has not retired. Keys
+// that are not activated yet are accepted, since another process may already sign with them.
+func lookupSigningKey(kid string) (*signingKey, error) {
+	find := func(keys []*signingKey) *signingKey {
+		for _, key := range keys {
+			if key.kid == kid {
+				return key
+			}
+		}
+		return nil
+	}
+
+	keys, err := cachedSigningKeys(signingKeyRefreshInterval)
+	if err != nil {
+		return nil, err
+	}
+	key := find(keys)
+	if key == nil {
+		// The key may have been added by another process since the last load
+		if keys, err = cachedSigningKeys(signingKeyMissInterval); err != nil {
+			return nil, err
+		}
+		key = find(keys)
+	}
+
+	if key == nil || key.retiredAt(time.Now()) {
+		return nil, ErrUnknownSigningKey
+	}
+	return key, nil
+}
+
+// cachedSigningKeys returns the cached keys, reloading them when they are older than maxAge.
+func cachedSigningKeys(maxAge time.Duration) ([]*signingKey, error) {
+	signingKeys.mu.RLock()
+	keys, loadedAt := signingKeys.keys, signingKeys.loadedAt
+	signingKeys.mu.RUnlock()
+
+	if time.Since(loadedAt) < maxAge {
+		return keys, nil
+	}
+	if err := loadSigningKeys(); err != nil {
+		return nil, err
+	}
+
+	signingKeys.mu.RLock()
+	defer signingKeys.mu.RUnlock()
+	return signingKeys.keys, nil
+}
+
+func loadSigningKeys() error {
+	var rows []entity.SigningKey
+	if err := database.DB.Where("retires_at IS NULL OR retires_at > ?", time.Now()).Find(&rows).Error; err != nil {
+		return err
+	}
+
+	keys := make([]*signingKey, 0, len(rows))
+	for _, row := range rows {
+		key, err := parseSigningKey(row)
+		if err != nil {
+			fmt.Printf("Error loading signing key %s: %v\n", row.Kid, err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].activatesAt.Before(keys[j].activatesAt)
+	})
+
+	signingKeys.mu.Lock()
+	signingKeys.keys = keys
+	signingKeys.loadedAt = time.Now()
+	signingKeys.mu.Unlock()
+	return nil
+}
+
+func parseSigningKey(row entity.SigningKey) (*signingKey, error) {
+	pemKey, err := decryptPrivateKey(row)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, errors.New("invalid PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{
+		kid:         row.Kid,
+		activatesAt: row.ActivatesAt,
+		retiresAt:   row.RetiresAt,
+	}
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private = jwt.SigningMethodRS256, private
+	case ed25519.PrivateKey:
+		key.method, key.private = jwt.SigningMethodEdDSA, private
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if key.method.Alg() != row.Algorithm {
+		return nil, fmt.Errorf("private key does not match algorithm %s", row.Algorithm)
+	}
+	return key, nil
+}
+
+// newSigningKey generates a key pair for the algorithm. Its kid is the RFC 7638 thumbprint of the public key.
+func newSigningKey(algorithm string) (*entity.SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case SigningAlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case SigningAlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	thumbprint, err := (&jose.JSONWebKey{Key: private.Public()}).Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := base64.RawURLEncoding.EncodeToString(thumbprint)
+	encrypted, err := encryptPrivateKey(kid, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.SigningKey{
+		Kid:        kid,
+		Algorithm:  algorithm,
+		PrivateKey: encrypted,
+	}, nil
+}
+
+// keyEncryptionCipher returns the AES-256-GCM cipher private keys and TOTP secrets are stored encrypted with. Its
+// key, JWT_KEY_ENCRYPTION_KEY, is kept out of the database so a leaked dump cannot be used to sign tokens or
+// generate second factor codes.
+func keyEncryptionCipher() (cipher.AEAD, error) {
+	kek, err := base64.StdEncoding.DecodeString(os.Getenv("JWT_KEY_ENCRYPTION_KEY"))
+	if err != nil || len(kek) != 32 {
+		return nil, ErrKeyEncryptionKey
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptPrivateKey encrypts a PEM private key. The kid is authenticated along with it, so a key cannot be
+// moved to another row.
+func encryptPrivateKey(kid string, pemKey []byte) (string, error) {
+	return encryptSecret(pemKey, kid)
+}
+
+func decryptPrivateKey(row entity.SigningKey) ([]byte, error) {
+	return decryptSecret(row.PrivateKey, row.Kid)
+}
+
+// encryptSecret encrypts a value stored in the database with the key encryption key. The associated data is
+// authenticated but not stored, so the value only decrypts where it was written.
+func encryptSecret(plaintext []byte, associated string) (string, error) {
+	aead, err := keyEncryptionCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(associated))
+	return encryptedKeyPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptSecret(value, associated string) ([]byte, error) {
+	if !strings.HasPrefix(value, encryptedKeyPrefix) {
+		return nil, errors.New("secret is not encrypted")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedKeyPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := keyEncryptionCipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted secret is too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(associated))
+}
+
+// encryptStoredSigningKeys encrypts the private keys that are still stored as plain PEM.
+func encryptStoredSigningKeys() error {
+	if _, err := keyEncryptionCipher(); err != nil {
+		return err
+	}
+
+	var rows []entity.SigningKey
+	if err := database.DB.Where("private_key NOT LIKE ?", encryptedKeyPrefix+"%").Find(&rows).Error; err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		encrypted, err := encryptPrivateKey(row.Kid, []byte(row.PrivateKey))
+		if err != nil {
+			return err
+		}
+		if err := database.DB.Model(&row).Update("private_key", encrypted).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"fmt"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"strings"
+
+	"github.com/gosimple/slug"
+	"gorm.io/gorm"
+)
+
+// maxSlugLength leaves room for a numeric suffix within the 100 characters of articles.slug.
+const maxSlugLength = 90
+
+// GenerateSlug turns text into a lowercase ASCII slug, transliterating non-ASCII characters.
+func GenerateSlug(text string) string {
+	generated := slug.Make(text)
+
+	if len(generated) > maxSlugLength {
+		generated = generated[:maxSlugLength]
+		if i := strings.LastIndex(generated, "-"); i > 0 {
+			generated = generated[:i]
+		}
+	}
+
+	if generated == "" {
+		generated = "article"
+	}
+
+	return generated
+}
+
+// SlugTaken reports whether a slug is used, now or in the past, by an article other than articleID.
+func SlugTaken(value string, articleID uint) (bool, error) {
+	var count int64
+	if err := database.DB.Model(&entity.Article{}).Where("slug = ? AND id <> ?", value, articleID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	if err := database.DB.Model(&entity.ArticleSlug{}).Where("slug = ? AND article_id <> ?", value, articleID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// UniqueArticleSlug returns base, or base with the lowest free numeric suffix such as base-2.
+func UniqueArticleSlug(base string, articleID uint) (string, error) {
+	for suffix := 1; ; suffix++ {
+		candidate := base
+		if suffix > 1 {
+			candidate = fmt.Sprintf("%s-%d", base, suffix)
+		}
+
+		taken, err := SlugTaken(candidate, articleID)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+	}
+}
+
+// RecordSlugChange keeps the old slug of a renamed article so it can be redirected to the new one.
+func RecordSlugChange(tx *gorm.DB, articleID uint, oldSlug, newSlug string) error {
+	if oldSlug == newSlug {
+		return nil
+	}
+
+	// The article may be taking back one of its own old slugs
+	if err := tx.Where("article_id = ? AND slug = ?", articleID, newSlug).Delete(&entity.ArticleSlug{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Where(entity.ArticleSlug{Slug: oldSlug}).
+		Assign(entity.ArticleSlug{ArticleID: articleID}).
+		FirstOrCreate(&entity.ArticleSlug{}).Error
+}
@@ -3,6 +3,8 @@ package utils
 import (
 	"go-news-api/database"
 	"go-news-api/models/entity"
+
+	"gorm.io/gorm"
 )
 
 func CreateOrFindTags(tagNames []string) ([]entity.Tag, error) {
@@ -31,28 +33,11 @@ func CreateOrFindTags(tagNames []string) ([]entity.Tag, error) {
 	return tags, nil
 }
 
-func AssociateTagsWithArticle(articleID uint, tags []entity.Tag) error {
-	tx := database.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
+func AssociateTagsWithArticle(tx *gorm.DB, articleID uint, tags []entity.Tag) error {
 	var article entity.Article
 	if err := tx.First(&article, articleID).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	if err := tx.Model(&article).Association("Tags").Replace(tags); err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	if err := tx.Commit().Error; err != nil {
 		return err
 	}
 
-	return nil
+	return tx.Model(&article).Association("Tags").Replace(tags)
 }
@@ -0,0 +1,276 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"image/png"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+)
+
+const (
+	twoFactorIssuer       = "Go News API"
+	recoveryCodeCount     = 10
+	twoFactorChallengeTyp = "2fa_challenge"
+	twoFactorChallengeTTL = 5 * time.Minute
+	// totpPeriod is the length of a TOTP time step in seconds.
+	totpPeriod = 30
+)
+
+var ErrInvalidChallengeToken = errors.New("invalid or expired challenge token")
+
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	QRCode string `json:"qr_code"`
+}
+
+// GenerateTwoFactorSecret creates a new TOTP secret for the user along with its otpauth:// URI
+// and a QR code PNG encoded as a data URI.
+func GenerateTwoFactorSecret(user *entity.User) (*TwoFactorEnrollment, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      twoFactorIssuer,
+		AccountName: user.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := key.Image(256, 256)
+	if err != nil {
+		return nil, err
+	}
+
+	var qrCode bytes.Buffer
+	if err := png.Encode(&qrCode, image); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnrollment{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrCode.Bytes()),
+	}, nil
+}
+
+// EncryptTwoFactorSecret encrypts a TOTP secret for storage. The user ID is authenticated along with it, so a
+// secret cannot be copied to another account.
+func EncryptTwoFactorSecret(userID uint, secret string) (string, error) {
+	return encryptSecret([]byte(secret), twoFactorSecretAssociatedData(userID))
+}
+
+func decryptTwoFactorSecret(user *entity.User) (string, error) {
+	secret, err := decryptSecret(user.TwoFactorSecret, twoFactorSecretAssociatedData(user.ID))
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+func twoFactorSecretAssociatedData(userID uint) string {
+	return "totp:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// EncryptStoredTwoFactorSecrets encrypts the TOTP secrets that are still stored in plain text.
+func EncryptStoredTwoFactorSecrets() error {
+	if _, err := keyEncryptionCipher(); err != nil {
+		return err
+	}
+
+	var users []entity.User
+	if err := database.DB.Select("id", "two_factor_secret").
+		Where("two_factor_secret <> '' AND two_factor_secret NOT LIKE ?", encryptedKeyPrefix+"%").
+		Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		encrypted, err := EncryptTwoFactorSecret(user.ID, user.TwoFactorSecret)
+		if err != nil {
+			return err
+		}
+		if err := database.DB.Model(&entity.User{}).Where("id = ?", user.ID).Update("two_factor_secret", encrypted).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseTOTP accepts a TOTP code from the previous, current or next time step and records its step, so every code
+// works once and no code older than the last accepted one works at all.
+func UseTOTP(user *entity.User, code string) (bool, error) {
+	if user.TwoFactorSecret == "" {
+		return false, nil
+	}
+
+	secret, err := decryptTwoFactorSecret(user)
+	if err != nil {
+		return false, err
+	}
+
+	code = strings.TrimSpace(code)
+	current := time.Now().Unix() / totpPeriod
+	for step := current - 1; step <= current+1; step++ {
+		if step <= user.TwoFactorLastStep {
+			continue
+		}
+
+		valid, err := totp.ValidateCustom(code, secret, time.Unix(step*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil || !valid {
+			continue
+		}
+
+		// Claim the step, so a concurrent request with the same code is rejected
+		result := database.DB.Model(&entity.User{}).
+			Where("id = ? AND two_factor_last_step < ?", user.ID, step).
+			Update("two_factor_last_step", step)
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return false, nil
+		}
+
+		user.TwoFactorLastStep = step
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// GenerateRecoveryCodes replaces the user's recovery codes with new ones and returns them in plain text.
+// Only their hashes are stored, so this is the only time they can be shown.
+func GenerateRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]entity.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		random := make([]byte, 6)
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(random))
+		code := raw[:5] + "-" + raw[5:]
+
+		codes = append(codes, code)
+		records = append(records, entity.RecoveryCode{UserID: userID, CodeHash: HashToken(code)})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// UseRecoveryCode consumes one of the user's unused recovery codes.
+func UseRecoveryCode(userID uint, code string) (bool, error) {
+	result := database.DB.Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, HashToken(strings.ToLower(strings.TrimSpace(code)))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// VerifySecondFactor accepts either a current TOTP code or an unused recovery code.
+func VerifySecondFactor(user *entity.User, code string) (bool, error) {
+	ok, err := UseTOTP(user, code)
+	if err != nil || ok {
+		return ok, err
+	}
+	return UseRecoveryCode(user.ID, code)
+}
+
+// GenerateTwoFactorChallenge issues the short-lived token a user exchanges, together with
+// a second factor, for a session after entering the right password.
+func GenerateTwoFactorChallenge(user *entity.User) (string, error) {
+	now := time.Now()
+	challenge := entity.TwoFactorChallenge{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		ExpiresAt: now.Add(twoFactorChallengeTTL),
+	}
+
+	// Forget challenges that were never exchanged
+	if err := database.DB.Where("expires_at < ?", now).Delete(&entity.TwoFactorChallenge{}).Error; err != nil {
+		return "", err
+	}
+
+	if err := database.DB.Create(&challenge).Error; err != nil {
+		return "", err
+	}
+
+	return GenerateToken(&jwt.MapClaims{
+		"user_id": user.ID,
+		"typ":     twoFactorChallengeTyp,
+		"jti":     challenge.ID,
+		"iat":     now.Unix(),
+		"exp":     challenge.ExpiresAt.Unix(),
+	})
+}
+
+// ParseTwoFactorChallenge returns the user ID and the challenge ID of a valid challenge token
+// that has not been exchanged yet.
+func ParseTwoFactorChallenge(challengeToken string) (uint, string, error) {
+	token, err := ParseToken(challengeToken)
+	if err != nil || !token.Valid {
+		return 0, "", ErrInvalidChallengeToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != twoFactorChallengeTyp {
+		return 0, "", ErrInvalidChallengeToken
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, "", ErrInvalidChallengeToken
+	}
+	challengeID, ok := claims["jti"].(string)
+	if !ok {
+		return 0, "", ErrInvalidChallengeToken
+	}
+
+	var challenge entity.TwoFactorChallenge
+	if err := database.DB.First(&challenge, "id = ? AND user_id = ? AND expires_at > ?", challengeID, uint(userID), time.Now()).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, "", ErrInvalidChallengeToken
+		}
+		return 0, "", err
+	}
+
+	return challenge.UserID, challenge.ID, nil
+}
+
+// ConsumeTwoFactorChallenge deletes the challenge once it is exchanged for a session, so its token cannot be used again.
+func ConsumeTwoFactorChallenge(challengeID string) error {
+	result := database.DB.Where("id = ? AND expires_at > ?", challengeID, time.Now()).Delete(&entity.TwoFactorChallenge{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidChallengeToken
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+var (
+	dummyPasswordHash     string
+	dummyPasswordHashOnce sync.Once
+)
+
+// AuthMinResponseTime is the least time the enumeration-protected auth endpoints take to respond.
+func AuthMinResponseTime() time.Duration {
+	return GetEnvDuration("AUTH_MIN_RESPONSE_TIME", 300*time.Millisecond)
+}
+
+// EqualizeResponseTime waits until the minimum response time has passed since start, so requests for unknown
+// and known accounts cannot be told apart by timing. Use it as `defer utils.EqualizeResponseTime(time.Now())`.
+func EqualizeResponseTime(start time.Time) {
+	if wait := AuthMinResponseTime() - time.Since(start); wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// VerifyDummyPassword spends the same work as VerifyPassword for accounts that do not exist. It always fails.
+func VerifyDummyPassword(password string) error {
+	dummyPasswordHashOnce.Do(func() {
+		token, _ := RandomToken(32)
+		dummyPasswordHash, _ = HashPassword(token)
+	})
+	return VerifyPassword(password, dummyPasswordHash)
+}
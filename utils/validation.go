@@ -38,6 +38,10 @@ func FormatValidationError(err validator.FieldError) string {
 		return err.Field() + " must be a valid email address"
 	case "eqfield":
 		return err.Field() + " must be equal to " + err.Param()
+	case "oneof":
+		return err.Field() + " must be one of " + err.Param()
+	case "datetime":
+		return err.Field() + " must be a valid date time in the format " + err.Param()
 	case "category_exists":
 		return "Category does not exist"
 	default:
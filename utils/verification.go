@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"errors"
+	"go-news-api/database"
+	"go-news-api/models/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmailNotVerifiedCode is the error code telling clients to have the user verify their email.
+const EmailNotVerifiedCode = "email_not_verified"
+
+var ErrVerificationRequired = errors.New("verify your email address to create content")
+
+// VerificationGrace is how long new accounts may create content before verifying their email. There is none by default.
+func VerificationGrace() time.Duration {
+	return GetEnvDuration("VERIFICATION_GRACE", 0)
+}
+
+// RequiresVerification reports whether the user has to verify their email before creating content.
+func RequiresVerification(user *entity.User) bool {
+	return !user.IsVerified && time.Since(user.CreatedAt) >= VerificationGrace()
+}
+
+// MarkUserVerified marks the email of the user as verified and drops any outstanding verification code.
+func MarkUserVerified(user *entity.User) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("is_verified", true).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND type = ?", user.ID, entity.EmailVerification).Delete(&entity.OtpCode{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	user.IsVerified = true
+	return nil
+}